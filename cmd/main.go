@@ -2,33 +2,221 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
+	"wecom-bot-server-go/internal/config"
 	"wecom-bot-server-go/internal/server"
 
 	mcpserver "github.com/mark3labs/mcp-go/server"
 )
 
 func main() {
+	// 加载配置：默认值 < 配置文件 < 环境变量 < 命令行参数
+	cfg, opts, err := config.Load(os.Args[1:], os.Getenv, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("加载配置失败: %v", err)
+	}
+	if opts.PrintConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			log.Fatalf("打印配置失败: %v", err)
+		}
+		return
+	}
+
+	if err := run(cfg); err != nil {
+		os.Exit(1)
+	}
+}
+
+// run 创建并运行服务器直到收到退出信号。错误在返回前记录到日志，
+// 并在日志文件关闭之前写出，避免 os.Exit 跳过 defer 导致最后的日志丢失
+func run(cfg *config.Config) (err error) {
+	// 日志，log 包的输出也会转发到这里
+	logger, closeLog, err := cfg.Logger()
+	if err != nil {
+		log.Printf("初始化日志失败: %v", err)
+		return err
+	}
+	defer closeLog()
+	slog.SetDefault(logger)
+	defer func() {
+		if err != nil {
+			slog.Error("服务器错误", "error", err.Error())
+		}
+	}()
+
 	// 创建 MCP 服务器
 	mcpServer := mcpserver.NewMCPServer(
-		"wecom-bot-server",
-		"2.0.0",
+		cfg.Server.Name,
+		cfg.Server.Version,
 		mcpserver.WithToolCapabilities(true),
+		mcpserver.WithResourceCapabilities(false, false),
 		mcpserver.WithRecovery(),
 		mcpserver.WithLogging(),
-		mcpserver.WithInstructions("该工具支持通过企业微信机器人向群聊发送文本、Markdown、图片、图文、模板卡片等多种类型的消息，并支持文件上传。每次调用可灵活指定 webhook_key，无需本地配置，适用于多机器人、多群场景。适合自动化推送通知、播报信息、群内互动等企业微信场景。"),
+		mcpserver.WithInstructions(server.Instructions(cfg.Bots, cfg.AllowRawKeys)),
 	)
 
+	serverOptions, err := cfg.ServerOptions()
+	if err != nil {
+		return fmt.Errorf("初始化服务器失败: %w", err)
+	}
+
 	// 创建服务器实例并注册工具
-	srv := server.New(mcpServer)
+	srv := server.New(mcpServer, serverOptions...)
 	if err := srv.RegisterTools(context.Background()); err != nil {
-		log.Fatalf("注册工具失败: %v", err)
+		return fmt.Errorf("注册工具失败: %w", err)
+	}
+	if err := srv.RegisterResources(context.Background()); err != nil {
+		return fmt.Errorf("注册资源失败: %w", err)
+	}
+
+	// 启动服务器，所有传输方式共用同一份工具注册；收到 SIGINT、SIGTERM 后优雅退出
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := serve(ctx, cfg, mcpServer, srv, logger); err != nil {
+		return err
+	}
+	slog.Info("服务器已退出")
+	return nil
+}
+
+// transport 可以优雅关闭的传输层
+type transport interface {
+	Shutdown(ctx context.Context) error
+}
+
+// stdioTransport 关闭时停止读取标准输入
+type stdioTransport struct {
+	cancel context.CancelFunc
+	done   <-chan error
+}
+
+// Shutdown 停止读取新的请求，并等待正在处理的请求写出响应
+func (t stdioTransport) Shutdown(ctx context.Context) error {
+	t.cancel()
+	select {
+	case <-t.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// serve 按配置的传输方式启动服务器，阻塞直到 ctx 结束后服务器完成关闭，或服务器出错。
+// stdio 模式下标准输出专用于协议消息，日志只写入标准错误或日志文件。
+func serve(ctx context.Context, cfg *config.Config, mcpServer *mcpserver.MCPServer, srv *server.Server, logger *slog.Logger) error {
+	errCh := make(chan error, 1)
+
+	if cfg.Server.Transport == config.TransportStdio {
+		stdioServer := mcpserver.NewStdioServer(mcpServer)
+		stdioServer.SetErrorLogger(slog.NewLogLogger(logger.Handler(), slog.LevelError))
+
+		listenCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		slog.Info("启动企业微信机器人 MCP stdio 服务器")
+		go func() { errCh <- stdioServer.Listen(listenCtx, os.Stdin, os.Stdout) }()
+
+		select {
+		case err := <-errCh:
+			// 客户端关闭了标准输入
+			return err
+		case <-ctx.Done():
+		}
+		return shutdown(cfg, srv, stdioTransport{cancel: cancel, done: errCh})
+	}
+
+	// HTTP 传输方式：配置了调用方时，所有请求先经过认证
+	authenticator, err := cfg.Authenticator()
+	if err != nil {
+		return err
+	}
+	protect := func(h http.Handler) http.Handler { return h }
+	if authenticator != nil {
+		protect = authenticator.Middleware
+	} else {
+		slog.Warn("未配置 auth.clients，HTTP 接口不做认证，任何能访问该地址的人都可以通过机器人发送消息")
+	}
+
+	tlsConfig, err := cfg.TLSServerConfig()
+	if err != nil {
+		return err
+	}
+	httpServer := &http.Server{Addr: cfg.Server.Listen, TLSConfig: tlsConfig}
+
+	var httpTransport transport
+	if cfg.Server.Transport == config.TransportSSE {
+		sseServer := mcpserver.NewSSEServer(mcpServer,
+			mcpserver.WithBaseURL(cfg.Server.PublicURL),
+			mcpserver.WithStaticBasePath(cfg.Server.BasePath),
+			mcpserver.WithHTTPServer(httpServer),
+		)
+		httpServer.Handler = protect(sseServer)
+		httpTransport = sseServer
+		slog.Info("启动企业微信机器人 MCP SSE 服务器", "listen", cfg.Server.Listen, "path", cfg.Server.BasePath, "tls", tlsConfig != nil)
+	} else {
+		streamableServer := mcpserver.NewStreamableHTTPServer(mcpServer,
+			mcpserver.WithEndpointPath(cfg.Server.BasePath),
+			mcpserver.WithStateLess(cfg.Server.Stateless),
+			mcpserver.WithStreamableHTTPServer(httpServer),
+		)
+		mux := http.NewServeMux()
+		mux.Handle(cfg.Server.BasePath, protect(streamableServer))
+		httpServer.Handler = mux
+		httpTransport = streamableServer
+		slog.Info("启动企业微信机器人 MCP Streamable-HTTP 服务器", "listen", cfg.Server.Listen, "path", cfg.Server.BasePath, "tls", tlsConfig != nil)
+	}
+
+	go func() { errCh <- listen(httpServer) }()
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
 	}
+	return shutdown(cfg, srv, httpTransport)
+}
+
+// shutdown 停止接受新的工具调用和连接，在超时时间内等待进行中的调用完成
+func shutdown(cfg *config.Config, srv *server.Server, t transport) error {
+	timeout := cfg.Server.ShutdownTimeout.Duration
+	slog.Info("收到退出信号，停止接受新的调用并等待进行中的调用完成", "timeout", timeout.String())
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
 
-	// 启动服务器
-	log.Println("启动企业微信机器人 MCP Streamable-HTTP 服务器，监听端口 :20301 ...")
-	if err := mcpserver.NewStreamableHTTPServer(mcpServer, mcpserver.WithStateLess(true)).Start(":20301"); err != nil {
-		log.Fatalf("服务器错误: %v", err)
+	// 先拒绝新的工具调用，再关闭传输层，避免关闭过程中被取消的请求中止正在发送的消息
+	srv.BeginShutdown()
+	transportErr := make(chan error, 1)
+	go func() { transportErr <- t.Shutdown(ctx) }()
+
+	drainErr := srv.Shutdown(ctx)
+	if err := <-transportErr; err != nil && drainErr == nil {
+		return fmt.Errorf("关闭传输层失败: %w", err)
+	}
+	return drainErr
+}
+
+// listen 配置了 TLS 时以 HTTPS 提供服务，证书由 TLSConfig.GetCertificate 提供。
+// 调用 Shutdown 后返回 nil。
+func listen(httpServer *http.Server) error {
+	var err error
+	if httpServer.TLSConfig != nil {
+		err = httpServer.ListenAndServeTLS("", "")
+	} else {
+		err = httpServer.ListenAndServe()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
 	}
+	return err
 }
@@ -2,7 +2,12 @@ module wecom-bot-server-go
 
 go 1.23.3
 
-require github.com/mark3labs/mcp-go v0.32.0
+require (
+	github.com/BurntSushi/toml v1.5.0
+	github.com/mark3labs/mcp-go v0.32.0
+	golang.org/x/image v0.25.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/google/uuid v1.6.0 // indirect
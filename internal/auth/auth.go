@@ -0,0 +1,243 @@
+// Package auth HTTP 接口的调用方认证，支持静态 Bearer Token、HMAC 签名请求和 mTLS 客户端证书，
+// 并按调用方限制可使用的机器人和工具
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// 认证方式
+const (
+	MethodToken = "token"
+	MethodHMAC  = "hmac"
+	MethodCert  = "cert"
+)
+
+// DefaultMaxSkew HMAC 签名请求的时间戳与服务器时间允许的最大偏差
+const DefaultMaxSkew = 5 * time.Minute
+
+// Client 允许访问 HTTP 接口的调用方，可以同时配置多种凭证，任意一种通过即可
+type Client struct {
+	// Name 调用方名称，用于日志和 HMAC 签名请求头
+	Name string `json:"name" yaml:"name" toml:"name"`
+	// Token 静态 Bearer Token
+	Token string `json:"token,omitempty" yaml:"token,omitempty" toml:"token,omitempty"`
+	// HMACSecret HMAC 签名密钥
+	HMACSecret string `json:"hmac_secret,omitempty" yaml:"hmac_secret,omitempty" toml:"hmac_secret,omitempty"`
+	// CertSubject 客户端证书的 Common Name，需要服务器启用 HTTPS 并配置客户端 CA 才能生效
+	CertSubject string `json:"cert_subject,omitempty" yaml:"cert_subject,omitempty" toml:"cert_subject,omitempty"`
+	// Bots 允许使用的机器人名称，支持 * 等通配符，为空时不限制
+	Bots []string `json:"bots,omitempty" yaml:"bots,omitempty" toml:"bots,omitempty"`
+	// Tools 允许调用的工具名称，支持 * 等通配符，为空时不限制
+	Tools []string `json:"tools,omitempty" yaml:"tools,omitempty" toml:"tools,omitempty"`
+}
+
+// Identity 通过认证的调用方
+type Identity struct {
+	Name string
+	// Method 认证方式：token、hmac 或 cert
+	Method string
+	Bots   []string
+	Tools  []string
+}
+
+// AllowsTool 判断调用方是否可以调用工具
+func (id *Identity) AllowsTool(name string) bool {
+	return matchAny(id.Tools, name)
+}
+
+// AllowsBot 判断调用方是否可以使用已登记的机器人
+func (id *Identity) AllowsBot(name string) bool {
+	return matchAny(id.Bots, name)
+}
+
+// AllowsRawKeys 判断调用方是否可以直接传入 webhook_key，只有机器人不受限制时才允许
+func (id *Identity) AllowsRawKeys() bool {
+	if len(id.Bots) == 0 {
+		return true
+	}
+	for _, pattern := range id.Bots {
+		if pattern == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAny 判断 name 是否匹配任一通配符模式，模式列表为空时总是匹配
+func matchAny(patterns []string, name string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+type identityKey struct{}
+
+// NewContext 返回携带调用方身份的 context
+func NewContext(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// FromContext 返回 context 中的调用方身份，未启用认证时返回 false
+func FromContext(ctx context.Context) (*Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(*Identity)
+	return id, ok
+}
+
+// ErrNoCredentials 请求未携带任何凭证
+var ErrNoCredentials = errors.New("请求未携带凭证")
+
+// Authenticator 按配置的调用方认证 HTTP 请求
+type Authenticator struct {
+	// tokens 以 Token 的 SHA-256 为键，避免按原文查找时泄露比较耗时
+	tokens map[[sha256.Size]byte]*Client
+	hmac   map[string]*Client
+	certs  map[string]*Client
+
+	maxSkew time.Duration
+	nonces  *nonceCache
+	now     func() time.Time
+}
+
+// Option 认证配置选项
+type Option func(*Authenticator)
+
+// WithMaxSkew 设置 HMAC 签名请求的时间戳允许的最大偏差
+func WithMaxSkew(skew time.Duration) Option {
+	return func(a *Authenticator) {
+		a.maxSkew = skew
+	}
+}
+
+// New 创建认证器，检查调用方名称和凭证是否完整且不重复
+func New(clients []Client, opts ...Option) (*Authenticator, error) {
+	a := &Authenticator{
+		tokens:  make(map[[sha256.Size]byte]*Client),
+		hmac:    make(map[string]*Client),
+		certs:   make(map[string]*Client),
+		maxSkew: DefaultMaxSkew,
+		nonces:  newNonceCache(DefaultMaxNonces),
+		now:     time.Now,
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	names := make(map[string]bool, len(clients))
+	for i := range clients {
+		client := &clients[i]
+		switch {
+		case client.Name == "":
+			return nil, fmt.Errorf("第 %d 个调用方缺少名称", i+1)
+		case names[client.Name]:
+			return nil, fmt.Errorf("调用方名称 %q 重复", client.Name)
+		case client.Token == "" && client.HMACSecret == "" && client.CertSubject == "":
+			return nil, fmt.Errorf("调用方 %q 至少需要配置 token、hmac_secret、cert_subject 之一", client.Name)
+		}
+		names[client.Name] = true
+
+		for _, pattern := range append(append([]string{}, client.Bots...), client.Tools...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("调用方 %q 的通配符 %q 格式错误", client.Name, pattern)
+			}
+		}
+		if client.Token != "" {
+			sum := sha256.Sum256([]byte(client.Token))
+			if _, ok := a.tokens[sum]; ok {
+				return nil, fmt.Errorf("调用方 %q 的 token 与其他调用方重复", client.Name)
+			}
+			a.tokens[sum] = client
+		}
+		if client.HMACSecret != "" {
+			a.hmac[client.Name] = client
+		}
+		if client.CertSubject != "" {
+			if _, ok := a.certs[client.CertSubject]; ok {
+				return nil, fmt.Errorf("调用方 %q 的 cert_subject 与其他调用方重复", client.Name)
+			}
+			a.certs[client.CertSubject] = client
+		}
+	}
+	return a, nil
+}
+
+// Authenticate 依次尝试 Bearer Token、HMAC 签名和客户端证书认证请求
+func (a *Authenticator) Authenticate(r *http.Request) (*Identity, error) {
+	if header := r.Header.Get("Authorization"); header != "" {
+		return a.authenticateToken(header)
+	}
+	if r.Header.Get(HeaderSignature) != "" {
+		return a.authenticateHMAC(r)
+	}
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		return a.authenticateCert(r)
+	}
+	return nil, ErrNoCredentials
+}
+
+// authenticateToken 校验 Bearer Token
+func (a *Authenticator) authenticateToken(header string) (*Identity, error) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return nil, errors.New("Authorization 请求头格式错误，应为 Bearer <token>")
+	}
+	client, ok := a.tokens[sha256.Sum256([]byte(token))]
+	if !ok {
+		return nil, errors.New("token 无效")
+	}
+	return newIdentity(client, MethodToken), nil
+}
+
+// authenticateCert 按已验证的客户端证书 Common Name 查找调用方
+func (a *Authenticator) authenticateCert(r *http.Request) (*Identity, error) {
+	subject := r.TLS.VerifiedChains[0][0].Subject.CommonName
+	client, ok := a.certs[subject]
+	if !ok {
+		return nil, fmt.Errorf("客户端证书 %q 未登记", subject)
+	}
+	return newIdentity(client, MethodCert), nil
+}
+
+// newIdentity 根据调用方配置创建身份
+func newIdentity(client *Client, method string) *Identity {
+	return &Identity{
+		Name:   client.Name,
+		Method: method,
+		Bots:   client.Bots,
+		Tools:  client.Tools,
+	}
+}
+
+// Middleware 拒绝未通过认证的请求并记录日志，通过认证的请求在 context 中携带调用方身份，
+// 工具处理函数可通过 FromContext 获取
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := a.Authenticate(r)
+		if err != nil {
+			slog.Warn("拒绝未通过认证的请求",
+				"remote", r.RemoteAddr,
+				"method", r.Method,
+				"path", r.URL.Path,
+				"reason", err.Error(),
+			)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="wecom-bot-server"`)
+			http.Error(w, "未通过认证: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), id)))
+	})
+}
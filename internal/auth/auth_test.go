@@ -0,0 +1,235 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestAuthenticator(t *testing.T) *Authenticator {
+	t.Helper()
+	a, err := New([]Client{
+		{Name: "ci-agent", Token: "ci-token", Bots: []string{"build-bot"}, Tools: []string{"send-markdown"}},
+		{Name: "support", HMACSecret: "support-secret", Bots: []string{"support-*"}},
+		{Name: "ops", CertSubject: "ops.example.com"},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return a
+}
+
+func TestAuthenticateToken(t *testing.T) {
+	a := newTestAuthenticator(t)
+
+	r := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+	r.Header.Set("Authorization", "Bearer ci-token")
+	id, err := a.Authenticate(r)
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if id.Name != "ci-agent" || id.Method != MethodToken {
+		t.Errorf("identity = %+v", id)
+	}
+
+	for _, header := range []string{"Bearer wrong", "Basic abc", "Bearer"} {
+		r.Header.Set("Authorization", header)
+		if _, err := a.Authenticate(r); err == nil {
+			t.Errorf("Authorization: %s 应认证失败", header)
+		}
+	}
+}
+
+func TestAuthenticateHMAC(t *testing.T) {
+	a := newTestAuthenticator(t)
+	now := time.Unix(1700000000, 0)
+	a.now = func() time.Time { return now }
+
+	body := `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`
+	newRequest := func(secret string, signedAt time.Time) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/mcp?x=1", strings.NewReader(body))
+		if err := SignRequest(r, "support", secret, signedAt); err != nil {
+			t.Fatalf("SignRequest() error = %v", err)
+		}
+		return r
+	}
+
+	r := newRequest("support-secret", now.Add(-time.Minute))
+	id, err := a.Authenticate(r)
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if id.Name != "support" || id.Method != MethodHMAC {
+		t.Errorf("identity = %+v", id)
+	}
+	if data, _ := io.ReadAll(r.Body); string(data) != body {
+		t.Errorf("认证后请求体 = %q", data)
+	}
+
+	if _, err := a.Authenticate(newRequest("wrong-secret", now)); err == nil || !strings.Contains(err.Error(), "签名无效") {
+		t.Errorf("错误密钥: err = %v", err)
+	}
+	if _, err := a.Authenticate(newRequest("support-secret", now.Add(-10*time.Minute))); err == nil || !strings.Contains(err.Error(), "时间戳") {
+		t.Errorf("过期签名: err = %v", err)
+	}
+
+	tampered := newRequest("support-secret", now)
+	tampered.Body = io.NopCloser(strings.NewReader(strings.Replace(body, "tools/list", "tools/call", 1)))
+	if _, err := a.Authenticate(tampered); err == nil {
+		t.Error("请求体被修改后应认证失败")
+	}
+}
+
+func TestAuthenticateHMACReplay(t *testing.T) {
+	a := newTestAuthenticator(t)
+	now := time.Unix(1700000000, 0)
+	a.now = func() time.Time { return now }
+
+	body := `{"jsonrpc":"2.0","id":1,"method":"tools/call"}`
+	r := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(body))
+	if err := SignRequest(r, "support", "support-secret", now); err != nil {
+		t.Fatalf("SignRequest() error = %v", err)
+	}
+	replay := func() *http.Request {
+		c := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(body))
+		c.Header = r.Header.Clone()
+		return c
+	}
+
+	missing := replay()
+	missing.Header.Del(HeaderNonce)
+	if _, err := a.Authenticate(missing); err == nil || !strings.Contains(err.Error(), HeaderNonce) {
+		t.Errorf("缺少 nonce: err = %v", err)
+	}
+
+	if _, err := a.Authenticate(replay()); err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	// 时间戳有效期内重放同一请求
+	now = now.Add(DefaultMaxSkew)
+	if _, err := a.Authenticate(replay()); err == nil || !strings.Contains(err.Error(), "重放") {
+		t.Errorf("重放请求: err = %v", err)
+	}
+	// 有效期过后时间戳本身被拒绝
+	now = now.Add(time.Second)
+	if _, err := a.Authenticate(replay()); err == nil || !strings.Contains(err.Error(), "时间戳") {
+		t.Errorf("过期后重放: err = %v", err)
+	}
+}
+
+func TestNonceCache(t *testing.T) {
+	c := newNonceCache(2)
+	now := time.Unix(1700000000, 0)
+
+	if err := c.use("a", now.Add(time.Minute), now); err != nil {
+		t.Fatalf("use(a) error = %v", err)
+	}
+	if err := c.use("a", now.Add(time.Minute), now); err == nil {
+		t.Error("重复的 nonce 应返回错误")
+	}
+	if err := c.use("b", now.Add(2*time.Minute), now); err != nil {
+		t.Fatalf("use(b) error = %v", err)
+	}
+	if err := c.use("c", now.Add(time.Minute), now); err != errNonceCacheFull {
+		t.Errorf("缓存已满时 err = %v", err)
+	}
+
+	// a 过期后释放空间，并且可以再次使用
+	now = now.Add(time.Minute + time.Second)
+	if err := c.use("a", now.Add(time.Minute), now); err != nil {
+		t.Errorf("过期后 use(a) error = %v", err)
+	}
+	if len(c.seen) != 2 {
+		t.Errorf("len(seen) = %d, want 2", len(c.seen))
+	}
+}
+
+func TestAuthenticateCert(t *testing.T) {
+	a := newTestAuthenticator(t)
+
+	newRequest := func(cn string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: cn}}
+		r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+		return r
+	}
+
+	id, err := a.Authenticate(newRequest("ops.example.com"))
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if id.Name != "ops" || id.Method != MethodCert {
+		t.Errorf("identity = %+v", id)
+	}
+	if _, err := a.Authenticate(newRequest("unknown.example.com")); err == nil {
+		t.Error("未登记的证书应认证失败")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	a := newTestAuthenticator(t)
+	var got *Identity
+	handler := a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = FromContext(r.Context())
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/mcp", nil))
+	if w.Code != http.StatusUnauthorized || got != nil {
+		t.Errorf("未携带凭证: code = %d, identity = %+v", w.Code, got)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+	r.Header.Set("Authorization", "Bearer ci-token")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || got == nil || got.Name != "ci-agent" {
+		t.Errorf("code = %d, identity = %+v", w.Code, got)
+	}
+}
+
+func TestIdentityScopes(t *testing.T) {
+	id := &Identity{Bots: []string{"support-*"}, Tools: []string{"send-*", "list-bots"}}
+	checks := []struct {
+		name string
+		got  bool
+		want bool
+	}{
+		{"support-cn", id.AllowsBot("support-cn"), true},
+		{"build-bot", id.AllowsBot("build-bot"), false},
+		{"send-text", id.AllowsTool("send-text"), true},
+		{"upload-file", id.AllowsTool("upload-file"), false},
+		{"raw keys", id.AllowsRawKeys(), false},
+		{"unrestricted raw keys", (&Identity{}).AllowsRawKeys(), true},
+		{"unrestricted bot", (&Identity{}).AllowsBot("any"), true},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestNewValidation(t *testing.T) {
+	tests := []struct {
+		clients []Client
+		want    string
+	}{
+		{[]Client{{Token: "t"}}, "缺少名称"},
+		{[]Client{{Name: "a"}}, "至少需要配置"},
+		{[]Client{{Name: "a", Token: "t"}, {Name: "a", Token: "u"}}, "重复"},
+		{[]Client{{Name: "a", Token: "t"}, {Name: "b", Token: "t"}}, "token"},
+		{[]Client{{Name: "a", Token: "t", Bots: []string{"["}}}, "通配符"},
+	}
+	for _, tt := range tests {
+		if _, err := New(tt.clients); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("New(%+v) error = %v, want containing %q", tt.clients, err, tt.want)
+		}
+	}
+}
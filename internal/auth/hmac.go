@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// HMAC 签名请求使用的请求头
+const (
+	// HeaderClient 调用方名称
+	HeaderClient = "X-WeCom-Bot-Client"
+	// HeaderTimestamp 签名时的 Unix 时间戳（秒）
+	HeaderTimestamp = "X-WeCom-Bot-Timestamp"
+	// HeaderNonce 每个请求唯一的随机字符串，用于拒绝重放
+	HeaderNonce = "X-WeCom-Bot-Nonce"
+	// HeaderSignature 十六进制编码的 HMAC-SHA256 签名
+	HeaderSignature = "X-WeCom-Bot-Signature"
+)
+
+// MaxSignedBodyBytes HMAC 签名请求允许的最大请求体，可容纳 Base64 编码的 20MB 文件
+const MaxSignedBodyBytes = 32 << 20
+
+// MaxNonceLength nonce 允许的最大长度
+const MaxNonceLength = 128
+
+// Sign 计算请求签名：
+// HMAC-SHA256(secret, 时间戳 + "\n" + nonce + "\n" + 方法 + "\n" + 请求路径和查询参数 + "\n" + 请求体的 SHA-256 十六进制)
+func Sign(secret string, timestamp int64, nonce, method, requestURI string, body []byte) string {
+	bodySum := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d\n%s\n%s\n%s\n%s", timestamp, nonce, method, requestURI, hex.EncodeToString(bodySum[:]))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignRequest 为请求设置 HMAC 签名请求头并生成随机 nonce，请求体会被读取后重新设置
+func SignRequest(r *http.Request, client, secret string, now time.Time) error {
+	var body []byte
+	if r.Body != nil {
+		var err error
+		body, err = io.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+		r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	timestamp := now.Unix()
+	var random [16]byte
+	if _, err := rand.Read(random[:]); err != nil {
+		return err
+	}
+	nonce := hex.EncodeToString(random[:])
+	r.Header.Set(HeaderClient, client)
+	r.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	r.Header.Set(HeaderNonce, nonce)
+	r.Header.Set(HeaderSignature, Sign(secret, timestamp, nonce, r.Method, r.URL.RequestURI(), body))
+	return nil
+}
+
+// authenticateHMAC 校验签名、时间戳和 nonce，校验通过后请求体可被再次读取。
+// 同一调用方的 nonce 在时间戳有效期内只能使用一次，截获的请求无法重放。
+func (a *Authenticator) authenticateHMAC(r *http.Request) (*Identity, error) {
+	name := r.Header.Get(HeaderClient)
+	client, ok := a.hmac[name]
+	if !ok {
+		return nil, fmt.Errorf("调用方 %q 未配置 HMAC 密钥", name)
+	}
+
+	timestamp, err := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s 请求头格式错误", HeaderTimestamp)
+	}
+	now := a.now()
+	if skew := now.Sub(time.Unix(timestamp, 0)).Abs(); skew > a.maxSkew {
+		return nil, fmt.Errorf("签名时间戳与服务器时间相差 %s，超过 %s", skew.Truncate(time.Second), a.maxSkew)
+	}
+	nonce := r.Header.Get(HeaderNonce)
+	if nonce == "" || len(nonce) > MaxNonceLength {
+		return nil, fmt.Errorf("%s 请求头不能为空且不能超过 %d 个字符", HeaderNonce, MaxNonceLength)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, MaxSignedBodyBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("读取请求体失败: %w", err)
+	}
+	if len(body) > MaxSignedBodyBytes {
+		return nil, fmt.Errorf("请求体超过 %d 字节限制", MaxSignedBodyBytes)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	want := Sign(client.HMACSecret, timestamp, nonce, r.Method, r.URL.RequestURI(), body)
+	if !hmac.Equal([]byte(want), []byte(r.Header.Get(HeaderSignature))) {
+		return nil, errors.New("签名无效")
+	}
+	// 签名通过后再登记 nonce，未持有密钥的请求无法占用缓存
+	expires := time.Unix(timestamp, 0).Add(a.maxSkew)
+	if err := a.nonces.use(client.Name+"\n"+nonce, expires, now); err != nil {
+		return nil, err
+	}
+	return newIdentity(client, MethodHMAC), nil
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// DefaultMaxNonces 重放保护最多记录的 nonce 数量
+const DefaultMaxNonces = 100000
+
+// errNonceCacheFull 有效期内的 nonce 数量达到上限，拒绝新的签名请求而不是忘记已使用的 nonce
+var errNonceCacheFull = errors.New("近期签名请求过多，请稍后重试")
+
+// nonceEntry 已使用的 nonce 及其过期时间
+type nonceEntry struct {
+	key     string
+	expires time.Time
+}
+
+// nonceCache 记录时间戳有效期内已使用的 nonce，拒绝重放的签名请求。
+// nonce 在签名时间戳超出允许偏差后过期，此时同一请求已因时间戳被拒绝，无需继续记录。
+type nonceCache struct {
+	mu    sync.Mutex
+	max   int
+	seen  map[string]time.Time
+	queue []nonceEntry
+}
+
+// newNonceCache 创建最多记录 max 个 nonce 的缓存
+func newNonceCache(max int) *nonceCache {
+	return &nonceCache{max: max, seen: make(map[string]time.Time)}
+}
+
+// use 登记 nonce，在 expires 及之前重复使用时返回错误
+func (c *nonceCache) use(key string, expires, now time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.expire(now)
+	if exp, ok := c.seen[key]; ok && !now.After(exp) {
+		return errors.New("nonce 已被使用，拒绝重放的请求")
+	}
+	if len(c.seen) >= c.max {
+		return errNonceCacheFull
+	}
+	c.seen[key] = expires
+	c.queue = append(c.queue, nonceEntry{key: key, expires: expires})
+	return nil
+}
+
+// expire 按登记顺序删除已过期的 nonce。过期时间不严格递增，未过期的条目会挡住后面已过期的条目，
+// 只会让后者晚一些删除
+func (c *nonceCache) expire(now time.Time) {
+	n := 0
+	for n < len(c.queue) && now.After(c.queue[n].expires) {
+		entry := c.queue[n]
+		if exp, ok := c.seen[entry.key]; ok && exp.Equal(entry.expires) {
+			delete(c.seen, entry.key)
+		}
+		n++
+	}
+	c.queue = c.queue[n:]
+}
@@ -0,0 +1,420 @@
+// Package config 加载服务器配置：默认值 < 配置文件 < 环境变量 < 命令行参数
+package config
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"wecom-bot-server-go/internal/auth"
+	"wecom-bot-server-go/internal/server"
+	"wecom-bot-server-go/internal/tlsconfig"
+	"wecom-bot-server-go/internal/wecom"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Duration 配置文件中以 "10s"、"1m" 形式书写的时间长度
+type Duration struct {
+	time.Duration
+}
+
+// MarshalText 实现 encoding.TextMarshaler 接口
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText 实现 encoding.TextUnmarshaler 接口
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
+
+// Config 服务器配置
+type Config struct {
+	Server     ServerConfig     `json:"server" yaml:"server" toml:"server"`
+	TLS        TLSConfig        `json:"tls" yaml:"tls" toml:"tls"`
+	WeCom      WeComConfig      `json:"wecom" yaml:"wecom" toml:"wecom"`
+	RateLimit  RateLimitConfig  `json:"rate_limit" yaml:"rate_limit" toml:"rate_limit"`
+	Files      FilesConfig      `json:"files" yaml:"files" toml:"files"`
+	MediaCache MediaCacheConfig `json:"media_cache" yaml:"media_cache" toml:"media_cache"`
+	Log        LogConfig        `json:"log" yaml:"log" toml:"log"`
+	Auth       AuthConfig       `json:"auth" yaml:"auth" toml:"auth"`
+	// Policy 发送和上传工具的授权策略，按调用方限制可使用的机器人和工具
+	Policy server.Policy `json:"policy" yaml:"policy" toml:"policy"`
+
+	// Bots 服务器端登记的机器人
+	Bots []server.Bot `json:"bots" yaml:"bots" toml:"bots"`
+	// BotsFile 额外的机器人配置文件（JSON 数组），与 Bots 合并
+	BotsFile string `json:"bots_file" yaml:"bots_file" toml:"bots_file"`
+	// AllowRawKeys 是否允许调用方直接传入 webhook_key
+	AllowRawKeys bool `json:"allow_raw_keys" yaml:"allow_raw_keys" toml:"allow_raw_keys"`
+}
+
+// ServerConfig MCP 服务器配置
+type ServerConfig struct {
+	Name    string `json:"name" yaml:"name" toml:"name"`
+	Version string `json:"version" yaml:"version" toml:"version"`
+	// Transport 传输方式：stdio、http（Streamable HTTP）或 sse
+	Transport string `json:"transport" yaml:"transport" toml:"transport"`
+	// Listen HTTP 监听地址，stdio 模式下忽略
+	Listen string `json:"listen" yaml:"listen" toml:"listen"`
+	// PublicURL 客户端访问服务器使用的地址，例如 "https://mcp.example.com"，
+	// sse 模式下用于生成消息接口地址，为空时使用相对路径
+	PublicURL string `json:"public_url" yaml:"public_url" toml:"public_url"`
+	// BasePath MCP 接口路径，sse 模式下为 {base_path}/sse 和 {base_path}/message
+	BasePath string `json:"base_path" yaml:"base_path" toml:"base_path"`
+	// Stateless 是否以无状态模式运行 Streamable HTTP，不保存会话
+	Stateless bool `json:"stateless" yaml:"stateless" toml:"stateless"`
+	// SplitInterval 超长消息拆分发送时相邻消息的间隔
+	SplitInterval Duration `json:"split_interval" yaml:"split_interval" toml:"split_interval"`
+	// ShutdownTimeout 收到 SIGINT、SIGTERM 后等待进行中的调用完成的时间
+	ShutdownTimeout Duration `json:"shutdown_timeout" yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+}
+
+// WeComConfig 企业微信接口配置
+type WeComConfig struct {
+	// BaseURL 机器人接口地址，用于私有化部署、出口代理或本地模拟服务
+	BaseURL string `json:"base_url" yaml:"base_url" toml:"base_url"`
+	// Timeout 单次请求超时时间，为 0 时不限制
+	Timeout Duration `json:"timeout" yaml:"timeout" toml:"timeout"`
+	// MaxAttempts 临时错误的最大尝试次数
+	MaxAttempts int    `json:"max_attempts" yaml:"max_attempts" toml:"max_attempts"`
+	UserAgent   string `json:"user_agent" yaml:"user_agent" toml:"user_agent"`
+}
+
+// RateLimitConfig 机器人发送频率限制
+type RateLimitConfig struct {
+	// Limit 时间窗口内每个机器人最多发送的消息数，为 0 时不限制
+	Limit  int      `json:"limit" yaml:"limit" toml:"limit"`
+	Window Duration `json:"window" yaml:"window" toml:"window"`
+	// Wait 超出限制时是否排队等待
+	Wait bool `json:"wait" yaml:"wait" toml:"wait"`
+}
+
+// FilesConfig 工具读取本地文件的限制
+type FilesConfig struct {
+	AllowedDirs       []string `json:"allowed_dirs" yaml:"allowed_dirs" toml:"allowed_dirs"`
+	AllowedExtensions []string `json:"allowed_extensions" yaml:"allowed_extensions" toml:"allowed_extensions"`
+	// MaxSize 允许读取的最大文件字节数，为 0 时不限制
+	MaxSize int64 `json:"max_size" yaml:"max_size" toml:"max_size"`
+}
+
+// MediaCacheConfig 素材缓存配置
+type MediaCacheConfig struct {
+	// File 持久化文件路径，为空时只保存在内存中
+	File string `json:"file" yaml:"file" toml:"file"`
+}
+
+// LogConfig 日志配置
+type LogConfig struct {
+	// Level 日志级别：debug、info、warn、error
+	Level string `json:"level" yaml:"level" toml:"level"`
+	// Format 日志格式：text 或 json
+	Format string `json:"format" yaml:"format" toml:"format"`
+	// File 日志文件路径，为空时输出到标准错误
+	File string `json:"file" yaml:"file" toml:"file"`
+}
+
+// TLSConfig HTTPS 配置，指定证书和私钥后以 TLS 提供 HTTP 服务，stdio 模式下忽略
+type TLSConfig struct {
+	// CertFile、KeyFile PEM 格式的服务器证书和私钥，文件更新后自动重新加载
+	CertFile string `json:"cert_file" yaml:"cert_file" toml:"cert_file"`
+	KeyFile  string `json:"key_file" yaml:"key_file" toml:"key_file"`
+	// MinVersion 最低 TLS 版本：1.2 或 1.3
+	MinVersion string `json:"min_version" yaml:"min_version" toml:"min_version"`
+	// ClientCAFile 校验客户端证书的 CA，用于 mTLS 认证
+	ClientCAFile string `json:"client_ca_file" yaml:"client_ca_file" toml:"client_ca_file"`
+	// RequireClientCert 是否拒绝未提供有效客户端证书的连接
+	RequireClientCert bool `json:"require_client_cert" yaml:"require_client_cert" toml:"require_client_cert"`
+}
+
+// Enabled 是否启用 TLS
+func (t TLSConfig) Enabled() bool {
+	return t.CertFile != "" || t.KeyFile != ""
+}
+
+// AuthConfig HTTP 接口认证配置，未配置调用方时不做认证，stdio 模式下忽略
+type AuthConfig struct {
+	// Clients 允许访问的调用方及其凭证和可使用的机器人、工具
+	Clients []auth.Client `json:"clients" yaml:"clients" toml:"clients"`
+	// MaxSkew HMAC 签名请求的时间戳允许的最大偏差
+	MaxSkew Duration `json:"max_skew" yaml:"max_skew" toml:"max_skew"`
+}
+
+// 支持的传输方式
+const (
+	// TransportStdio 通过标准输入输出通信，适用于以 command 方式启动服务器的桌面客户端
+	TransportStdio = "stdio"
+	// TransportHTTP Streamable HTTP
+	TransportHTTP = "http"
+	// TransportSSE HTTP + SSE，兼容只支持旧版协议的客户端
+	TransportSSE = "sse"
+)
+
+// Default 返回默认配置
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Name:            "wecom-bot-server",
+			Version:         "2.0.0",
+			Transport:       TransportHTTP,
+			Listen:          ":20301",
+			BasePath:        "/mcp",
+			Stateless:       true,
+			SplitInterval:   Duration{server.DefaultSplitInterval},
+			ShutdownTimeout: Duration{server.DefaultShutdownTimeout},
+		},
+		WeCom: WeComConfig{
+			BaseURL:     wecom.WeComBotBaseURL,
+			MaxAttempts: wecom.DefaultRetryPolicy.MaxAttempts,
+			UserAgent:   wecom.DefaultUserAgent,
+		},
+		RateLimit: RateLimitConfig{
+			Limit:  server.DefaultRateLimit.Limit,
+			Window: Duration{server.DefaultRateLimit.Window},
+			Wait:   server.DefaultRateLimit.Wait,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "text",
+		},
+		TLS: TLSConfig{
+			MinVersion: "1.2",
+		},
+		Auth: AuthConfig{
+			MaxSkew: Duration{auth.DefaultMaxSkew},
+		},
+		AllowRawKeys: true,
+	}
+}
+
+// LoadFile 按扩展名（.yaml、.yml、.json、.toml）解析配置文件，覆盖 c 中对应的字段
+func (c *Config) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("读取配置文件失败: %w", err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("解析配置文件 %s 失败: %w", path, err)
+		}
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(c); err != nil {
+			return fmt.Errorf("解析配置文件 %s 失败: %w", path, err)
+		}
+	case ".toml":
+		meta, err := toml.Decode(string(data), c)
+		if err != nil {
+			return fmt.Errorf("解析配置文件 %s 失败: %w", path, err)
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("解析配置文件 %s 失败: 未知的配置项 %s", path, undecoded[0])
+		}
+	default:
+		return fmt.Errorf("不支持的配置文件格式 %q，可选 .yaml、.yml、.json、.toml", ext)
+	}
+	return nil
+}
+
+// Validate 检查配置是否有效
+func (c *Config) Validate() error {
+	switch c.Server.Transport {
+	case TransportStdio, TransportHTTP, TransportSSE:
+	default:
+		return fmt.Errorf("server.transport 必须为 stdio、http 或 sse，实际为 %q", c.Server.Transport)
+	}
+	if c.Server.Transport != TransportStdio && c.Server.Listen == "" {
+		return errors.New("server.listen 不能为空")
+	}
+	if !strings.HasPrefix(c.Server.BasePath, "/") {
+		return fmt.Errorf("server.base_path 必须以 / 开头，实际为 %q", c.Server.BasePath)
+	}
+	if c.TLS.Enabled() && (c.TLS.CertFile == "" || c.TLS.KeyFile == "") {
+		return errors.New("tls.cert_file 和 tls.key_file 需要同时指定")
+	}
+	if !c.TLS.Enabled() && (c.TLS.ClientCAFile != "" || c.TLS.RequireClientCert) {
+		return errors.New("配置客户端证书校验时需要指定 tls.cert_file 和 tls.key_file")
+	}
+	if _, err := tlsconfig.ParseVersion(c.TLS.MinVersion); err != nil {
+		return fmt.Errorf("tls.min_version 配置错误: %w", err)
+	}
+	if c.Server.ShutdownTimeout.Duration <= 0 {
+		return errors.New("server.shutdown_timeout 必须大于 0")
+	}
+	if c.WeCom.MaxAttempts < 1 {
+		return fmt.Errorf("wecom.max_attempts 必须大于 0，实际为 %d", c.WeCom.MaxAttempts)
+	}
+	if c.RateLimit.Limit > 0 && c.RateLimit.Window.Duration <= 0 {
+		return errors.New("rate_limit.window 必须大于 0")
+	}
+	if _, err := c.Log.level(); err != nil {
+		return err
+	}
+	if c.Log.Format != "text" && c.Log.Format != "json" {
+		return fmt.Errorf("log.format 必须为 text 或 json，实际为 %q", c.Log.Format)
+	}
+	if err := server.ValidateBots(c.Bots); err != nil {
+		return fmt.Errorf("bots 配置错误: %w", err)
+	}
+	if !c.AllowRawKeys && len(c.Bots) == 0 {
+		return errors.New("allow_raw_keys 为 false 时至少需要登记一个机器人")
+	}
+	if _, err := c.Authenticator(); err != nil {
+		return fmt.Errorf("auth 配置错误: %w", err)
+	}
+	if err := c.Policy.Validate(); err != nil {
+		return fmt.Errorf("policy 配置错误: %w", err)
+	}
+	return nil
+}
+
+// TLSServerConfig 创建 HTTPS 使用的 TLS 配置，未启用 TLS 时返回 nil
+func (c *Config) TLSServerConfig() (*tls.Config, error) {
+	if !c.TLS.Enabled() {
+		return nil, nil
+	}
+	return tlsconfig.New(tlsconfig.Options{
+		CertFile:          c.TLS.CertFile,
+		KeyFile:           c.TLS.KeyFile,
+		ClientCAFile:      c.TLS.ClientCAFile,
+		RequireClientCert: c.TLS.RequireClientCert,
+		MinVersion:        c.TLS.MinVersion,
+	})
+}
+
+// Authenticator 创建 HTTP 接口认证器，未配置调用方时返回 nil
+func (c *Config) Authenticator() (*auth.Authenticator, error) {
+	if len(c.Auth.Clients) == 0 {
+		return nil, nil
+	}
+	return auth.New(c.Auth.Clients, auth.WithMaxSkew(c.Auth.MaxSkew.Duration))
+}
+
+// level 解析日志级别
+func (l LogConfig) level() (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(l.Level)); err != nil {
+		return 0, fmt.Errorf("log.level 必须为 debug、info、warn 或 error，实际为 %q", l.Level)
+	}
+	return level, nil
+}
+
+// ClientOptions 返回企业微信客户端选项
+func (c *Config) ClientOptions() []wecom.Option {
+	policy := wecom.DefaultRetryPolicy
+	policy.MaxAttempts = c.WeCom.MaxAttempts
+	opts := []wecom.Option{
+		wecom.WithBaseURL(c.WeCom.BaseURL),
+		wecom.WithUserAgent(c.WeCom.UserAgent),
+		wecom.WithRetryPolicy(policy),
+	}
+	if c.WeCom.Timeout.Duration > 0 {
+		opts = append(opts, wecom.WithTimeout(c.WeCom.Timeout.Duration))
+	}
+	return opts
+}
+
+// ServerOptions 返回 MCP 服务器选项，会加载素材缓存
+func (c *Config) ServerOptions() ([]server.Option, error) {
+	mediaCache, err := wecom.NewMediaCache(c.MediaCache.File)
+	if err != nil {
+		return nil, err
+	}
+
+	var extensions []string
+	for _, ext := range c.Files.AllowedExtensions {
+		extensions = append(extensions, "."+strings.TrimPrefix(ext, "."))
+	}
+
+	return []server.Option{
+		server.WithClientOptions(c.ClientOptions()...),
+		server.WithRateLimit(server.RateLimit{
+			Limit:  c.RateLimit.Limit,
+			Window: c.RateLimit.Window.Duration,
+			Wait:   c.RateLimit.Wait,
+		}),
+		server.WithSplitInterval(c.Server.SplitInterval.Duration),
+		server.WithMediaCache(mediaCache),
+		server.WithFileAccess(server.FileAccess{
+			AllowedRoots:      c.Files.AllowedDirs,
+			AllowedExtensions: extensions,
+			MaxBytes:          c.Files.MaxSize,
+		}),
+		server.WithBots(c.Bots...),
+		server.WithRawWebhookKeys(c.AllowRawKeys),
+		server.WithPolicy(c.Policy),
+	}, nil
+}
+
+// Logger 按日志配置创建日志记录器，返回的关闭函数用于关闭日志文件
+func (c *Config) Logger() (*slog.Logger, func() error, error) {
+	level, err := c.Log.level()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var w io.Writer = os.Stderr
+	closeFn := func() error { return nil }
+	if c.Log.File != "" {
+		file, err := os.OpenFile(c.Log.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("打开日志文件失败: %w", err)
+		}
+		w = file
+		closeFn = file.Close
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	if c.Log.Format == "json" {
+		return slog.New(slog.NewJSONHandler(w, opts)), closeFn, nil
+	}
+	return slog.New(slog.NewTextHandler(w, opts)), closeFn, nil
+}
+
+// Redacted 返回隐藏了 webhook key 和认证凭证的配置副本，用于打印
+func (c *Config) Redacted() *Config {
+	redacted := *c
+	redacted.Bots = make([]server.Bot, len(c.Bots))
+	for i, bot := range c.Bots {
+		bot.WebhookKey = redactKey(bot.WebhookKey)
+		redacted.Bots[i] = bot
+	}
+	redacted.Auth.Clients = make([]auth.Client, len(c.Auth.Clients))
+	for i, client := range c.Auth.Clients {
+		client.Token = redactKey(client.Token)
+		client.HMACSecret = redactKey(client.HMACSecret)
+		redacted.Auth.Clients[i] = client
+	}
+	return &redacted
+}
+
+// redactKey 只保留 key 的最后 4 个字符，空值保持为空
+func redactKey(key string) string {
+	if key == "" {
+		return ""
+	}
+	if len(key) <= 4 {
+		return "****"
+	}
+	return "****" + key[len(key)-4:]
+}
@@ -0,0 +1,239 @@
+package config
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// envMap 测试用的环境变量
+func envMap(env map[string]string) func(string) string {
+	return func(key string) string {
+		return env[key]
+	}
+}
+
+// writeFile 在临时目录中写入文件并返回路径
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("写入文件失败: %v", err)
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, opts, err := Load(nil, envMap(nil), io.Discard)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if opts.PrintConfig {
+		t.Error("PrintConfig 默认应为 false")
+	}
+	if cfg.Server.Listen != ":20301" || cfg.Server.BasePath != "/mcp" || !cfg.Server.Stateless {
+		t.Errorf("Server = %+v", cfg.Server)
+	}
+	if !cfg.AllowRawKeys {
+		t.Error("AllowRawKeys 默认应为 true")
+	}
+	if cfg.Server.ShutdownTimeout.Duration != 30*time.Second {
+		t.Errorf("ShutdownTimeout = %v", cfg.Server.ShutdownTimeout)
+	}
+}
+
+func TestLoadFileFormats(t *testing.T) {
+	files := map[string]string{
+		"config.yaml": `
+server:
+  listen: ":9000"
+  split_interval: 1s
+wecom:
+  timeout: 5s
+bots:
+  - name: ops
+    webhook_key: key-ops
+`,
+		"config.json": `{
+  "server": {"listen": ":9000", "split_interval": "1s"},
+  "wecom": {"timeout": "5s"},
+  "bots": [{"name": "ops", "webhook_key": "key-ops"}]
+}`,
+		"config.toml": `
+[server]
+listen = ":9000"
+split_interval = "1s"
+
+[wecom]
+timeout = "5s"
+
+[[bots]]
+name = "ops"
+webhook_key = "key-ops"
+`,
+	}
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			path := writeFile(t, name, content)
+			cfg, _, err := Load([]string{"-config", path}, envMap(nil), io.Discard)
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if cfg.Server.Listen != ":9000" {
+				t.Errorf("Listen = %q", cfg.Server.Listen)
+			}
+			if cfg.Server.SplitInterval.Duration != time.Second {
+				t.Errorf("SplitInterval = %v", cfg.Server.SplitInterval)
+			}
+			if cfg.WeCom.Timeout.Duration != 5*time.Second {
+				t.Errorf("Timeout = %v", cfg.WeCom.Timeout)
+			}
+			// 未出现在文件中的配置项保持默认值
+			if cfg.Server.BasePath != "/mcp" {
+				t.Errorf("BasePath = %q", cfg.Server.BasePath)
+			}
+			if len(cfg.Bots) != 1 || cfg.Bots[0].WebhookKey != "key-ops" {
+				t.Errorf("Bots = %+v", cfg.Bots)
+			}
+		})
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, "config.yaml", "server:\n  listen: \":9000\"\n  base_path: /file\nrate_limit:\n  limit: 5\n")
+	env := envMap(map[string]string{
+		"WECOM_BOT_CONFIG":     path,
+		"WECOM_BOT_LISTEN":     ":9100",
+		"WECOM_BOT_RATE_LIMIT": "10",
+	})
+
+	cfg, _, err := Load([]string{"-listen", ":9200", "-stateless=false"}, env, io.Discard)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Server.Listen != ":9200" {
+		t.Errorf("Listen = %q，命令行参数应覆盖环境变量", cfg.Server.Listen)
+	}
+	if cfg.RateLimit.Limit != 10 {
+		t.Errorf("RateLimit.Limit = %d，环境变量应覆盖配置文件", cfg.RateLimit.Limit)
+	}
+	if cfg.Server.BasePath != "/file" {
+		t.Errorf("BasePath = %q", cfg.Server.BasePath)
+	}
+	if cfg.Server.Stateless {
+		t.Error("Stateless 应被 -stateless=false 关闭")
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		env     map[string]string
+		file    string
+		content string
+		want    string
+	}{
+		{name: "未知配置项", file: "config.yaml", args: nil, want: "解析配置文件"},
+		{name: "环境变量格式错误", env: map[string]string{"WECOM_BOT_TIMEOUT": "abc"}, want: "WECOM_BOT_TIMEOUT"},
+		{name: "参数格式错误", args: []string{"-max-attempts", "x"}, want: "-max-attempts"},
+		{name: "路径不以斜杠开头", args: []string{"-base-path", "mcp"}, want: "base_path"},
+		{name: "禁止直接传入 key 但未登记机器人", args: []string{"-allow-raw-keys=false"}, want: "allow_raw_keys"},
+		{name: "日志级别无效", args: []string{"-log-level", "verbose"}, want: "log.level"},
+		{name: "传输方式无效", args: []string{"-transport", "grpc"}, want: "server.transport"},
+		{name: "关闭等待时间无效", env: map[string]string{"WECOM_BOT_SHUTDOWN_TIMEOUT": "0s"}, want: "server.shutdown_timeout"},
+		{name: "策略无效", file: "config.yaml", content: "policy:\n  rules:\n    - effect: permit\n", want: "policy"},
+		{name: "只指定证书", args: []string{"-tls-cert", "cert.pem"}, want: "tls.cert_file"},
+		{name: "TLS 版本无效", args: []string{"-tls-min-version", "1.0"}, want: "tls.min_version"},
+		{name: "多余参数", args: []string{"extra"}, want: "无法识别的参数"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.args
+			if tt.file != "" {
+				content := tt.content
+				if content == "" {
+					content = "server:\n  unknown: 1\n"
+				}
+				path := writeFile(t, tt.file, content)
+				args = append([]string{"-config", path}, args...)
+			}
+			_, _, err := Load(args, envMap(tt.env), io.Discard)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load() error = %v, want containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadTransport(t *testing.T) {
+	cfg, _, err := Load([]string{"-transport", "stdio", "-listen", ""}, envMap(nil), io.Discard)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Server.Transport != TransportStdio {
+		t.Errorf("Transport = %q", cfg.Server.Transport)
+	}
+
+	// HTTP 传输方式需要监听地址
+	if _, _, err := Load([]string{"-transport", "sse", "-listen", ""}, envMap(nil), io.Discard); err == nil {
+		t.Error("sse 模式下监听地址为空时应返回错误")
+	}
+}
+
+func TestLoadBotsFile(t *testing.T) {
+	botsFile := writeFile(t, "bots.json", `[{"name": "dev", "webhook_key": "key-dev"}]`)
+	path := writeFile(t, "config.yaml", "bots:\n  - name: ops\n    webhook_key: key-ops\nallow_raw_keys: false\n")
+
+	cfg, _, err := Load([]string{"-config", path, "-bots-file", botsFile}, envMap(nil), io.Discard)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(cfg.Bots) != 2 || cfg.Bots[0].Name != "ops" || cfg.Bots[1].Name != "dev" {
+		t.Errorf("Bots = %+v", cfg.Bots)
+	}
+	if _, err := cfg.ServerOptions(); err != nil {
+		t.Errorf("ServerOptions() error = %v", err)
+	}
+}
+
+func TestPrintConfig(t *testing.T) {
+	path := writeFile(t, "config.yaml", "bots:\n  - name: ops\n    webhook_key: secret-key-1234\n"+
+		"auth:\n  clients:\n    - name: ci\n      token: secret-token-5678\n      bots: [ops]\n")
+	cfg, opts, err := Load([]string{"-config", path, "-print-config"}, envMap(nil), io.Discard)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !opts.PrintConfig {
+		t.Error("PrintConfig 应为 true")
+	}
+
+	var buf bytes.Buffer
+	if err := cfg.Print(&buf); err != nil {
+		t.Fatalf("Print() error = %v", err)
+	}
+	out := buf.String()
+	if strings.Contains(out, "secret-key") || strings.Contains(out, "secret-token") {
+		t.Errorf("输出中包含 webhook key 或 token:\n%s", out)
+	}
+	for _, want := range []string{"****1234", "****5678", "listen: :20301", "split_interval: 500ms"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("输出中缺少 %q:\n%s", want, out)
+		}
+	}
+	if cfg.Bots[0].WebhookKey != "secret-key-1234" {
+		t.Error("Print 不应修改原配置")
+	}
+
+	// 打印的配置可以重新加载
+	reloaded := Default()
+	if err := reloaded.LoadFile(writeFile(t, "printed.yaml", out)); err != nil {
+		t.Errorf("重新加载打印的配置失败: %v", err)
+	}
+}
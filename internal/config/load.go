@@ -0,0 +1,257 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"wecom-bot-server-go/internal/server"
+
+	"gopkg.in/yaml.v3"
+)
+
+// setting 一个可通过环境变量和命令行参数覆盖的配置项
+type setting struct {
+	flag   string
+	env    string
+	usage  string
+	isBool bool
+	set    func(c *Config, value string) error
+}
+
+// settings 所有可覆盖的配置项，环境变量沿用早期版本的名称
+var settings = []setting{
+	{"transport", "WECOM_BOT_TRANSPORT", "传输方式：stdio、http 或 sse", false, func(c *Config, v string) error {
+		c.Server.Transport = v
+		return nil
+	}},
+	{"listen", "WECOM_BOT_LISTEN", "HTTP 监听地址", false, func(c *Config, v string) error {
+		c.Server.Listen = v
+		return nil
+	}},
+	{"public-url", "WECOM_BOT_PUBLIC_URL", "客户端访问服务器使用的地址，sse 模式下用于生成消息接口地址", false, func(c *Config, v string) error {
+		c.Server.PublicURL = v
+		return nil
+	}},
+	{"base-path", "WECOM_BOT_BASE_PATH", "MCP 接口路径", false, func(c *Config, v string) error {
+		c.Server.BasePath = v
+		return nil
+	}},
+	{"stateless", "WECOM_BOT_STATELESS", "以无状态模式运行 Streamable HTTP", true, func(c *Config, v string) error {
+		return parseBool(v, &c.Server.Stateless)
+	}},
+	{"shutdown-timeout", "WECOM_BOT_SHUTDOWN_TIMEOUT", "收到退出信号后等待进行中的调用完成的时间", false, func(c *Config, v string) error {
+		return parseDuration(v, &c.Server.ShutdownTimeout)
+	}},
+	{"tls-cert", "WECOM_BOT_TLS_CERT", "HTTPS 证书文件（PEM）", false, func(c *Config, v string) error {
+		c.TLS.CertFile = v
+		return nil
+	}},
+	{"tls-key", "WECOM_BOT_TLS_KEY", "HTTPS 私钥文件（PEM）", false, func(c *Config, v string) error {
+		c.TLS.KeyFile = v
+		return nil
+	}},
+	{"tls-min-version", "WECOM_BOT_TLS_MIN_VERSION", "最低 TLS 版本：1.2 或 1.3", false, func(c *Config, v string) error {
+		c.TLS.MinVersion = v
+		return nil
+	}},
+	{"tls-client-ca", "WECOM_BOT_TLS_CLIENT_CA", "校验客户端证书的 CA 文件（PEM）", false, func(c *Config, v string) error {
+		c.TLS.ClientCAFile = v
+		return nil
+	}},
+	{"tls-require-client-cert", "WECOM_BOT_TLS_REQUIRE_CLIENT_CERT", "拒绝未提供有效客户端证书的连接", true, func(c *Config, v string) error {
+		return parseBool(v, &c.TLS.RequireClientCert)
+	}},
+	{"split-interval", "WECOM_BOT_SPLIT_INTERVAL", "超长消息拆分发送时相邻消息的间隔", false, func(c *Config, v string) error {
+		return parseDuration(v, &c.Server.SplitInterval)
+	}},
+	{"base-url", "WECOM_BOT_BASE_URL", "企业微信机器人接口地址", false, func(c *Config, v string) error {
+		c.WeCom.BaseURL = v
+		return nil
+	}},
+	{"timeout", "WECOM_BOT_TIMEOUT", "单次请求超时时间，例如 10s", false, func(c *Config, v string) error {
+		return parseDuration(v, &c.WeCom.Timeout)
+	}},
+	{"max-attempts", "WECOM_BOT_MAX_ATTEMPTS", "临时错误的最大尝试次数", false, func(c *Config, v string) error {
+		return parseInt(v, &c.WeCom.MaxAttempts)
+	}},
+	{"rate-limit", "WECOM_BOT_RATE_LIMIT", "每个机器人每分钟最多发送的消息数，0 表示不限制", false, func(c *Config, v string) error {
+		return parseInt(v, &c.RateLimit.Limit)
+	}},
+	{"rate-limit-wait", "WECOM_BOT_RATE_LIMIT_WAIT", "超出发送频率时排队等待", true, func(c *Config, v string) error {
+		return parseBool(v, &c.RateLimit.Wait)
+	}},
+	{"allowed-dirs", "WECOM_BOT_ALLOWED_DIRS", "允许工具读取的目录，多个目录用系统路径分隔符分隔", false, func(c *Config, v string) error {
+		c.Files.AllowedDirs = filepath.SplitList(v)
+		return nil
+	}},
+	{"allowed-extensions", "WECOM_BOT_ALLOWED_EXTENSIONS", "允许工具读取的文件扩展名，用逗号分隔", false, func(c *Config, v string) error {
+		c.Files.AllowedExtensions = splitComma(v)
+		return nil
+	}},
+	{"max-file-size", "WECOM_BOT_MAX_FILE_SIZE", "允许工具读取的最大文件字节数", false, func(c *Config, v string) error {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return err
+		}
+		c.Files.MaxSize = n
+		return nil
+	}},
+	{"media-cache-file", "WECOM_BOT_MEDIA_CACHE_FILE", "素材缓存持久化文件", false, func(c *Config, v string) error {
+		c.MediaCache.File = v
+		return nil
+	}},
+	{"bots-file", "WECOM_BOT_BOTS_FILE", "机器人配置文件（JSON 数组）", false, func(c *Config, v string) error {
+		c.BotsFile = v
+		return nil
+	}},
+	{"allow-raw-keys", "WECOM_BOT_ALLOW_RAW_KEYS", "允许调用方直接传入 webhook_key", true, func(c *Config, v string) error {
+		return parseBool(v, &c.AllowRawKeys)
+	}},
+	{"policy-dry-run", "WECOM_BOT_POLICY_DRY_RUN", "只记录授权策略的结果不拒绝调用", true, func(c *Config, v string) error {
+		return parseBool(v, &c.Policy.DryRun)
+	}},
+	{"log-level", "WECOM_BOT_LOG_LEVEL", "日志级别：debug、info、warn、error", false, func(c *Config, v string) error {
+		c.Log.Level = v
+		return nil
+	}},
+	{"log-format", "WECOM_BOT_LOG_FORMAT", "日志格式：text 或 json", false, func(c *Config, v string) error {
+		c.Log.Format = v
+		return nil
+	}},
+	{"log-file", "WECOM_BOT_LOG_FILE", "日志文件路径，为空时输出到标准错误", false, func(c *Config, v string) error {
+		c.Log.File = v
+		return nil
+	}},
+}
+
+// Options 命令行中与配置内容无关的参数
+type Options struct {
+	// ConfigFile 配置文件路径
+	ConfigFile string
+	// PrintConfig 只打印生效的配置并退出
+	PrintConfig bool
+}
+
+// flagValue 命令行中出现的配置项，按出现顺序应用
+type flagValue struct {
+	setting setting
+	value   string
+}
+
+// Load 依次应用默认值、配置文件、环境变量和命令行参数，并检查最终配置。
+// getenv 通常为 os.Getenv，output 用于输出帮助信息。
+func Load(args []string, getenv func(string) string, output io.Writer) (*Config, Options, error) {
+	var opts Options
+	var values []flagValue
+
+	fs := flag.NewFlagSet("wecom-bot-server", flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.StringVar(&opts.ConfigFile, "config", getenv("WECOM_BOT_CONFIG"), "配置文件路径，支持 .yaml、.yml、.json、.toml（环境变量 WECOM_BOT_CONFIG）")
+	fs.BoolVar(&opts.PrintConfig, "print-config", false, "检查配置并打印生效的配置后退出")
+	for _, s := range settings {
+		s := s
+		record := func(v string) error {
+			values = append(values, flagValue{setting: s, value: v})
+			return nil
+		}
+		usage := fmt.Sprintf("%s（环境变量 %s）", s.usage, s.env)
+		if s.isBool {
+			fs.BoolFunc(s.flag, usage, record)
+		} else {
+			fs.Func(s.flag, usage, record)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, opts, err
+	}
+	if fs.NArg() > 0 {
+		return nil, opts, fmt.Errorf("无法识别的参数: %s", strings.Join(fs.Args(), " "))
+	}
+
+	cfg := Default()
+	if opts.ConfigFile != "" {
+		if err := cfg.LoadFile(opts.ConfigFile); err != nil {
+			return nil, opts, err
+		}
+	}
+	for _, s := range settings {
+		if v := getenv(s.env); v != "" {
+			if err := s.set(cfg, v); err != nil {
+				return nil, opts, fmt.Errorf("环境变量 %s 格式错误: %w", s.env, err)
+			}
+		}
+	}
+	for _, fv := range values {
+		if err := fv.setting.set(cfg, fv.value); err != nil {
+			return nil, opts, fmt.Errorf("参数 -%s 格式错误: %w", fv.setting.flag, err)
+		}
+	}
+
+	if cfg.BotsFile != "" {
+		bots, err := server.LoadBots(cfg.BotsFile)
+		if err != nil {
+			return nil, opts, err
+		}
+		cfg.Bots = append(cfg.Bots, bots...)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, opts, fmt.Errorf("配置错误: %w", err)
+	}
+	return cfg, opts, nil
+}
+
+// Print 以 YAML 格式输出配置，webhook key 会被隐藏
+func (c *Config) Print(w io.Writer) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c.Redacted()); err != nil {
+		return fmt.Errorf("序列化配置失败: %w", err)
+	}
+	return encoder.Close()
+}
+
+// parseBool 解析布尔值
+func parseBool(v string, dst *bool) error {
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return err
+	}
+	*dst = b
+	return nil
+}
+
+// parseInt 解析整数
+func parseInt(v string, dst *int) error {
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return err
+	}
+	*dst = n
+	return nil
+}
+
+// parseDuration 解析时间长度，例如 "500ms"、"10s"
+func parseDuration(v string, dst *Duration) error {
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return err
+	}
+	dst.Duration = d
+	return nil
+}
+
+// splitComma 按逗号拆分并去除空白项
+func splitComma(v string) []string {
+	var list []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"wecom-bot-server-go/internal/auth"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// authorize 检查通过 HTTP 认证的调用方是否有权调用工具并使用参数中指定的机器人，
+// tool 为空时只检查机器人。未启用认证（例如 stdio 模式）时不做限制
+func (s *Server) authorize(ctx context.Context, tool string, args map[string]any) error {
+	id, ok := auth.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	if tool != "" && !id.AllowsTool(tool) {
+		return fmt.Errorf("调用方 %q 无权使用工具 %s", id.Name, tool)
+	}
+
+	name, _ := args["bot"].(string)
+	key, _ := args["webhook_key"].(string)
+	if name != "" && !id.AllowsBot(name) {
+		return fmt.Errorf("调用方 %q 无权使用机器人 %q", id.Name, name)
+	}
+	if key != "" && !id.AllowsRawKeys() {
+		return fmt.Errorf("调用方 %q 只能通过 bot 参数使用允许的机器人，不能直接传入 webhook_key", id.Name)
+	}
+	return nil
+}
+
+// authorized 在工具处理函数之前检查调用方权限，拒绝时记录日志并返回工具错误
+func (s *Server) authorized(tool string, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if err := s.authorize(ctx, tool, request.GetArguments()); err != nil {
+			slog.Warn("拒绝工具调用", "tool", tool, "reason", err.Error())
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		return handler(ctx, request)
+	}
+}
+
+// allowsBot 判断调用方是否可以查看机器人，未启用认证时总是允许
+func allowsBot(ctx context.Context, name string) bool {
+	id, ok := auth.FromContext(ctx)
+	return !ok || id.AllowsBot(name)
+}
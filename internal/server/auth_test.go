@@ -0,0 +1,56 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"wecom-bot-server-go/internal/auth"
+)
+
+func TestAuthorizeScopes(t *testing.T) {
+	ts := newTestServer(t, WithBots(
+		Bot{Name: "build-bot", WebhookKey: "build-key"},
+		Bot{Name: "support-cn", WebhookKey: "support-key"},
+	))
+	ctx := auth.NewContext(context.Background(), &auth.Identity{
+		Name:  "ci-agent",
+		Bots:  []string{"build-*"},
+		Tools: []string{"send-markdown", "list-bots"},
+	})
+
+	text, isErr := ts.callContext(ctx, t, "send-markdown", map[string]any{"bot": "build-bot", "content": "构建成功"})
+	if isErr {
+		t.Fatalf("发送失败: %s", text)
+	}
+
+	tests := []struct {
+		tool string
+		args map[string]any
+		want string
+	}{
+		{"send-text", map[string]any{"bot": "build-bot", "content": "hi"}, "无权使用工具 send-text"},
+		{"send-markdown", map[string]any{"bot": "support-cn", "content": "hi"}, "无权使用机器人"},
+		{"send-markdown", map[string]any{"webhook_key": "raw-key", "content": "hi"}, "不能直接传入 webhook_key"},
+	}
+	for _, tt := range tests {
+		text, isErr := ts.callContext(ctx, t, tt.tool, tt.args)
+		if !isErr || !strings.Contains(text, tt.want) {
+			t.Errorf("%s %v: %s，期望包含 %q", tt.tool, tt.args, text, tt.want)
+		}
+	}
+	if len(ts.keys) != 1 {
+		t.Errorf("被拒绝的调用不应发送消息，keys = %v", ts.keys)
+	}
+
+	// list-bots 只列出调用方可以使用的机器人
+	text, isErr = ts.callContext(ctx, t, "list-bots", map[string]any{})
+	if isErr || !strings.Contains(text, "build-bot") || strings.Contains(text, "support-cn") {
+		t.Errorf("list-bots = %s", text)
+	}
+
+	// 未启用认证时不做限制
+	if text, isErr := ts.call(t, "send-text", map[string]any{"bot": "support-cn", "content": "hi"}); isErr {
+		t.Errorf("未认证的调用被拒绝: %s", text)
+	}
+}
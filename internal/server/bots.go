@@ -0,0 +1,243 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Bot 在服务器端登记的机器人，调用方通过名称引用，webhook key 不会出现在提示词和调用记录中
+type Bot struct {
+	// Name 机器人名称，例如 "ops-alerts"
+	Name string `json:"name" yaml:"name" toml:"name"`
+	// Description 机器人用途说明，帮助调用方选择要发送的群
+	Description string `json:"description,omitempty" yaml:"description,omitempty" toml:"description,omitempty"`
+	// WebhookKey 机器人的 webhook key
+	WebhookKey string `json:"webhook_key" yaml:"webhook_key" toml:"webhook_key"`
+	// RateLimit 每分钟最多发送的消息数，小于等于 0 时使用默认限制
+	RateLimit int `json:"rate_limit,omitempty" yaml:"rate_limit,omitempty" toml:"rate_limit,omitempty"`
+}
+
+// botInfo 对外展示的机器人信息，不包含 webhook key
+type botInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// botsURI 机器人列表资源的 URI
+const botsURI = "wecom://bots"
+
+// WithBots 登记机器人，名称重复时后登记的覆盖先登记的
+func WithBots(bots ...Bot) Option {
+	return func(s *Server) {
+		for _, bot := range bots {
+			if _, ok := s.bots[bot.Name]; !ok {
+				s.botNames = append(s.botNames, bot.Name)
+			}
+			s.bots[bot.Name] = bot
+		}
+	}
+}
+
+// WithRawWebhookKeys 设置是否允许调用方直接传入 webhook_key，为 false 时只能通过 bot 参数使用已登记的机器人
+func WithRawWebhookKeys(allowed bool) Option {
+	return func(s *Server) {
+		s.allowRawKeys = allowed
+	}
+}
+
+// LoadBots 从 JSON 文件加载机器人列表，文件格式为 Bot 数组
+func LoadBots(path string) ([]Bot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取机器人配置失败: %w", err)
+	}
+	var bots []Bot
+	if err := json.Unmarshal(data, &bots); err != nil {
+		return nil, fmt.Errorf("解析机器人配置失败: %w", err)
+	}
+	if err := ValidateBots(bots); err != nil {
+		return nil, err
+	}
+	return bots, nil
+}
+
+// ValidateBots 检查机器人名称和 webhook key 是否完整且名称不重复
+func ValidateBots(bots []Bot) error {
+	seen := make(map[string]bool, len(bots))
+	for i, bot := range bots {
+		switch {
+		case bot.Name == "":
+			return fmt.Errorf("第 %d 个机器人缺少名称", i+1)
+		case bot.WebhookKey == "":
+			return fmt.Errorf("机器人 %q 缺少 webhook_key", bot.Name)
+		case seen[bot.Name]:
+			return fmt.Errorf("机器人名称 %q 重复", bot.Name)
+		}
+		seen[bot.Name] = true
+	}
+	return nil
+}
+
+// applyBotRateLimits 为设置了发送频率的机器人单独限流，已通过 WithKeyRateLimit 设置的保持不变
+func (s *Server) applyBotRateLimits() {
+	for _, name := range s.botNames {
+		bot := s.bots[name]
+		if bot.RateLimit <= 0 {
+			continue
+		}
+		if _, ok := s.limiter.overrides[bot.WebhookKey]; ok {
+			continue
+		}
+		limit := s.limiter.defaultLimit
+		limit.Limit = bot.RateLimit
+		s.limiter.overrides[bot.WebhookKey] = limit
+	}
+}
+
+// botToolOptions 所有发送工具共用的机器人参数
+func (s *Server) botToolOptions() []mcp.ToolOption {
+	var opts []mcp.ToolOption
+	if len(s.botNames) > 0 {
+		opts = append(opts, mcp.WithString("bot",
+			mcp.Description("已登记的机器人名称，可通过 list-bots 工具查看"),
+			mcp.Enum(s.botNames...),
+		))
+	}
+	if s.allowRawKeys {
+		desc := "企业微信机器人的Webhook Key"
+		if len(s.botNames) > 0 {
+			desc += "，与 bot 二选一，建议优先使用 bot"
+		}
+		keyOpts := []mcp.PropertyOption{mcp.Description(desc)}
+		// 没有登记机器人时每次调用都必须传入 webhook key
+		if len(s.botNames) == 0 {
+			keyOpts = append(keyOpts, mcp.Required())
+		}
+		opts = append(opts, mcp.WithString("webhook_key", keyOpts...))
+	}
+	return opts
+}
+
+// Instructions 根据机器人配置生成提供给 MCP 客户端的服务器说明
+func Instructions(bots []Bot, allowRawKeys bool) string {
+	var b strings.Builder
+	b.WriteString("该工具支持通过企业微信机器人向群聊发送文本、Markdown、图片、图文、模板卡片等多种类型的消息，并支持文件上传。")
+	switch {
+	case len(bots) == 0:
+		b.WriteString("每次调用需通过 webhook_key 参数指定机器人，适用于多机器人、多群场景。")
+	case allowRawKeys:
+		b.WriteString("服务器已登记机器人，调用时通过 bot 参数指定名称（可用 list-bots 工具查看），也可以通过 webhook_key 参数直接指定未登记的机器人，两者二选一。")
+	default:
+		b.WriteString("调用时通过 bot 参数指定已登记的机器人名称，可用 list-bots 工具查看，服务器不接受直接传入的 webhook_key。")
+	}
+	b.WriteString("适合自动化推送通知、播报信息、群内互动等企业微信场景。")
+	return b.String()
+}
+
+// addTool 为工具添加机器人参数，在调用前检查调用方权限和授权策略，并跟踪进行中的调用以便关闭时等待
+func (s *Server) addTool(tool mcp.Tool, handler server.ToolHandlerFunc) {
+	for _, opt := range s.botToolOptions() {
+		opt(&tool)
+	}
+	s.mcpServer.AddTool(tool, s.tracked(tool.Name, s.authorized(tool.Name, s.enforcePolicy(tool.Name, handler))))
+}
+
+// webhookKey 根据 bot 或 webhook_key 参数确定要使用的 webhook key
+func (s *Server) webhookKey(args map[string]any) (string, error) {
+	name, _ := args["bot"].(string)
+	key, _ := args["webhook_key"].(string)
+
+	switch {
+	case name != "" && key != "":
+		return "", errors.New("bot和webhook_key参数只能提供其中之一")
+	case name != "":
+		bot, ok := s.bots[name]
+		if !ok {
+			return "", fmt.Errorf("未找到名为 %q 的机器人，可通过 list-bots 工具查看已登记的机器人", name)
+		}
+		return bot.WebhookKey, nil
+	case key != "":
+		if !s.allowRawKeys {
+			return "", errors.New("服务器已禁止直接传入 webhook_key，请通过 bot 参数指定已登记的机器人")
+		}
+		return key, nil
+	case !s.allowRawKeys:
+		return "", errors.New("bot参数必须是非空字符串")
+	case len(s.botNames) > 0:
+		return "", errors.New("bot或webhook_key参数必须提供其中之一")
+	default:
+		return "", errors.New("webhook_key参数必须是非空字符串")
+	}
+}
+
+// botList 返回调用方可以使用的机器人列表
+func (s *Server) botList(ctx context.Context) []botInfo {
+	list := make([]botInfo, 0, len(s.botNames))
+	for _, name := range s.botNames {
+		if !allowsBot(ctx, name) {
+			continue
+		}
+		bot := s.bots[name]
+		list = append(list, botInfo{Name: bot.Name, Description: bot.Description})
+	}
+	return list
+}
+
+// registerListBotsTool 注册列出机器人工具
+func (s *Server) registerListBotsTool() error {
+	tool := mcp.NewTool("list-bots",
+		mcp.WithDescription("列出服务器端已登记的机器人名称和用途说明，发送消息时通过 bot 参数指定"),
+	)
+
+	s.mcpServer.AddTool(tool, s.authorized(tool.Name, s.handleListBots))
+	return nil
+}
+
+// handleListBots 处理列出机器人
+func (s *Server) handleListBots(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	bots := s.botList(ctx)
+	if len(bots) == 0 {
+		return mcp.NewToolResultText("服务器未登记任何机器人"), nil
+	}
+
+	lines := make([]string, 0, len(bots))
+	for _, bot := range bots {
+		line := "- " + bot.Name
+		if bot.Description != "" {
+			line += ": " + bot.Description
+		}
+		lines = append(lines, line)
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("已登记 %d 个机器人：\n%s", len(lines), strings.Join(lines, "\n"))), nil
+}
+
+// registerBotsResource 注册机器人列表资源
+func (s *Server) registerBotsResource() {
+	resource := mcp.NewResource(botsURI, "bots",
+		mcp.WithResourceDescription("服务器端已登记的机器人名称和用途说明，不包含 webhook key"),
+		mcp.WithMIMEType("application/json"),
+	)
+	s.mcpServer.AddResource(resource, s.handleReadBots)
+}
+
+// handleReadBots 返回机器人列表
+func (s *Server) handleReadBots(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	data, err := json.MarshalIndent(s.botList(ctx), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("序列化机器人列表失败: %w", err)
+	}
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      botsURI,
+			MIMEType: "application/json",
+			Text:     string(data),
+		},
+	}, nil
+}
@@ -0,0 +1,135 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestSendWithBotName(t *testing.T) {
+	ts := newTestServer(t,
+		WithBots(
+			Bot{Name: "ops-alerts", Description: "运维告警群", WebhookKey: "ops-key", RateLimit: 5},
+			Bot{Name: "daily", WebhookKey: "daily-key"},
+		),
+		WithRawWebhookKeys(false),
+	)
+
+	text, isErr := ts.call(t, "send-text", map[string]any{"bot": "ops-alerts", "content": "磁盘告警"})
+	if isErr {
+		t.Fatalf("发送失败: %s", text)
+	}
+	if len(ts.keys) != 1 || ts.keys[0] != "ops-key" {
+		t.Errorf("keys = %v", ts.keys)
+	}
+
+	tests := []struct {
+		args map[string]any
+		want string
+	}{
+		{map[string]any{"webhook_key": "raw-key", "content": "hi"}, "禁止"},
+		{map[string]any{"bot": "unknown", "content": "hi"}, "未找到"},
+		{map[string]any{"bot": "daily", "webhook_key": "raw-key", "content": "hi"}, "只能提供其中之一"},
+		{map[string]any{"content": "hi"}, "bot参数"},
+	}
+	for _, tt := range tests {
+		text, isErr := ts.call(t, "send-text", tt.args)
+		if !isErr || !strings.Contains(text, tt.want) {
+			t.Errorf("args = %v: %s，期望包含 %q", tt.args, text, tt.want)
+		}
+	}
+
+	if limit := ts.limiter.limitFor("ops-key"); limit.Limit != 5 {
+		t.Errorf("ops-alerts 限流 = %+v", limit)
+	}
+	if limit := ts.limiter.limitFor("daily-key"); limit.Limit != DefaultRateLimit.Limit {
+		t.Errorf("daily 限流 = %+v", limit)
+	}
+}
+
+func TestBotToolSchema(t *testing.T) {
+	ts := newTestServer(t, WithBots(Bot{Name: "ops-alerts", WebhookKey: "ops-key"}), WithRawWebhookKeys(false))
+
+	raw, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": "tools/list"})
+	resp, ok := ts.mcpServer.HandleMessage(context.Background(), raw).(mcp.JSONRPCResponse)
+	if !ok {
+		t.Fatal("获取工具列表失败")
+	}
+	result, _ := resp.Result.(mcp.ListToolsResult)
+	for _, tool := range result.Tools {
+		if tool.Name == "list-bots" {
+			continue
+		}
+		if _, ok := tool.InputSchema.Properties["webhook_key"]; ok {
+			t.Errorf("%s 不应包含 webhook_key 参数", tool.Name)
+		}
+		if _, ok := tool.InputSchema.Properties["bot"]; !ok {
+			t.Errorf("%s 缺少 bot 参数", tool.Name)
+		}
+	}
+
+	// 没有登记机器人时 webhook_key 为必填参数
+	ts = newTestServer(t)
+	resp, ok = ts.mcpServer.HandleMessage(context.Background(), raw).(mcp.JSONRPCResponse)
+	if !ok {
+		t.Fatal("获取工具列表失败")
+	}
+	result, _ = resp.Result.(mcp.ListToolsResult)
+	for _, tool := range result.Tools {
+		if tool.Name == "list-bots" {
+			continue
+		}
+		if !slices.Contains(tool.InputSchema.Required, "webhook_key") {
+			t.Errorf("%s 的 webhook_key 应为必填参数，required = %v", tool.Name, tool.InputSchema.Required)
+		}
+		if _, ok := tool.InputSchema.Properties["bot"]; ok {
+			t.Errorf("%s 不应包含 bot 参数", tool.Name)
+		}
+	}
+}
+
+func TestInstructions(t *testing.T) {
+	bots := []Bot{{Name: "ops-alerts", WebhookKey: "ops-key"}}
+	if text := Instructions(nil, true); !strings.Contains(text, "webhook_key") || strings.Contains(text, "bot 参数") {
+		t.Errorf("未登记机器人时 = %s", text)
+	}
+	if text := Instructions(bots, true); !strings.Contains(text, "bot 参数") || !strings.Contains(text, "webhook_key 参数") {
+		t.Errorf("允许直接传入 key 时 = %s", text)
+	}
+	if text := Instructions(bots, false); !strings.Contains(text, "不接受直接传入的 webhook_key") {
+		t.Errorf("禁止直接传入 key 时 = %s", text)
+	}
+}
+
+func TestListBots(t *testing.T) {
+	ts := newTestServer(t, WithBots(Bot{Name: "ops-alerts", Description: "运维告警群", WebhookKey: "ops-key"}))
+	text, isErr := ts.call(t, "list-bots", map[string]any{})
+	if isErr || !strings.Contains(text, "ops-alerts: 运维告警群") || strings.Contains(text, "ops-key") {
+		t.Errorf("list-bots = %s", text)
+	}
+}
+
+func TestLoadBots(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bots.json")
+	os.WriteFile(path, []byte(`[{"name":"ops-alerts","webhook_key":"ops-key","rate_limit":10}]`), 0o600)
+	bots, err := LoadBots(path)
+	if err != nil {
+		t.Fatalf("LoadBots failed: %v", err)
+	}
+	if len(bots) != 1 || bots[0].WebhookKey != "ops-key" || bots[0].RateLimit != 10 {
+		t.Errorf("bots = %+v", bots)
+	}
+
+	if err := ValidateBots([]Bot{{Name: "a", WebhookKey: "1"}, {Name: "a", WebhookKey: "2"}}); err == nil {
+		t.Error("名称重复时应返回错误")
+	}
+	if err := ValidateBots([]Bot{{Name: "a"}}); err == nil {
+		t.Error("缺少 webhook_key 时应返回错误")
+	}
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// FileAccess 工具通过 file_path 参数读取服务器本地文件的限制，零值表示禁止读取本地文件
+type FileAccess struct {
+	// AllowedRoots 允许读取的目录，为空时禁止通过 file_path 读取文件；相对路径按第一个目录解析
+	AllowedRoots []string
+	// AllowedExtensions 允许的扩展名，例如 ".pdf"，不区分大小写，为空时不限制
+	AllowedExtensions []string
+	// MaxBytes 允许读取的最大文件字节数，小于等于 0 时不限制
+	MaxBytes int64
+}
+
+// WithFileAccess 限制工具可以读取的本地文件，防止调用方将服务器上的任意文件发送到群聊
+func WithFileAccess(access FileAccess) Option {
+	return func(s *Server) {
+		s.fileAccess = access
+	}
+}
+
+// testHookBeforeOpen 测试用，在检查路径之后、打开文件之前调用
+var testHookBeforeOpen func()
+
+// open 检查路径是否在允许的范围内并打开文件。符号链接和 ".." 会先被解析为真实路径，
+// 再判断是否位于允许的目录内。检查与打开之间路径中的目录可能被替换为符号链接，
+// 因此在 Linux 上打开后还会通过 /proc/self/fd 取得实际打开的文件路径并再次检查；
+// 其他系统无法取得该路径，只能依赖打开前的检查，无法防止这种竞争。
+func (a FileAccess) open(path string) (*os.File, error) {
+	if path == "" {
+		return nil, errors.New("文件路径不能为空")
+	}
+	if len(a.AllowedRoots) == 0 {
+		return nil, errors.New("服务器未配置允许读取的目录（files.allowed_dirs），不能通过 file_path 读取本地文件，请改为直接传入文件内容")
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(a.AllowedRoots[0], path)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("文件路径 %s 无效: %w", path, err)
+	}
+	real, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("文件 %s 不存在", path)
+		}
+		return nil, fmt.Errorf("无法访问文件 %s: %w", path, err)
+	}
+
+	if !a.withinRoots(real) {
+		return nil, fmt.Errorf("文件 %s 不在允许访问的目录内，允许的目录: %s", path, strings.Join(a.AllowedRoots, ", "))
+	}
+	if len(a.AllowedExtensions) > 0 {
+		ext := strings.ToLower(filepath.Ext(real))
+		if !slices.ContainsFunc(a.AllowedExtensions, func(allowed string) bool { return strings.EqualFold(allowed, ext) }) {
+			return nil, fmt.Errorf("不允许读取扩展名为 %q 的文件，允许的扩展名: %s", ext, strings.Join(a.AllowedExtensions, ", "))
+		}
+	}
+
+	if testHookBeforeOpen != nil {
+		testHookBeforeOpen()
+	}
+	info, err := os.Stat(real)
+	if err != nil {
+		return nil, fmt.Errorf("无法访问文件 %s: %w", path, err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s 不是普通文件", path)
+	}
+	if a.MaxBytes > 0 && info.Size() > a.MaxBytes {
+		return nil, fmt.Errorf("文件 %s 大小为 %d 字节，超过允许的 %d 字节", path, info.Size(), a.MaxBytes)
+	}
+
+	file, err := os.Open(real)
+	if err != nil {
+		return nil, fmt.Errorf("打开文件失败: %w", err)
+	}
+	// 实际打开的文件必须仍在允许的目录内
+	if opened, ok := openedPath(file); ok && !a.withinRoots(opened) {
+		file.Close()
+		return nil, fmt.Errorf("文件 %s 不在允许访问的目录内，允许的目录: %s", path, strings.Join(a.AllowedRoots, ", "))
+	}
+	// 文件本身可能在检查后被替换，确认打开的仍是检查过的文件
+	opened, err := file.Stat()
+	if err != nil || !os.SameFile(info, opened) {
+		file.Close()
+		return nil, fmt.Errorf("文件 %s 在检查期间发生变化，请重试", path)
+	}
+	return file, nil
+}
+
+// withinRoots 判断真实路径是否位于某个允许的目录内
+func (a FileAccess) withinRoots(real string) bool {
+	for _, root := range a.AllowedRoots {
+		abs, err := filepath.Abs(root)
+		if err != nil {
+			continue
+		}
+		// 允许的目录本身也可能是符号链接
+		if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+			abs = resolved
+		}
+		rel, err := filepath.Rel(abs, real)
+		if err != nil {
+			continue
+		}
+		if rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,15 @@
+package server
+
+import (
+	"os"
+	"strconv"
+)
+
+// openedPath 通过 /proc/self/fd 获取已打开文件的真实路径，路径中的符号链接已被内核解析
+func openedPath(file *os.File) (string, bool) {
+	path, err := os.Readlink("/proc/self/fd/" + strconv.Itoa(int(file.Fd())))
+	if err != nil {
+		return "", false
+	}
+	return path, true
+}
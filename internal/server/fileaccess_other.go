@@ -0,0 +1,10 @@
+//go:build !linux
+
+package server
+
+import "os"
+
+// openedPath 非 Linux 系统无法可靠获取已打开文件的路径，只能依赖打开前的检查
+func openedPath(*os.File) (string, bool) {
+	return "", false
+}
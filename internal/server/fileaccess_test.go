@@ -0,0 +1,129 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestFileAccessOpen(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	write := func(path, content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("写入文件失败: %v", err)
+		}
+	}
+	write(filepath.Join(root, "report.pdf"), "report content")
+	write(filepath.Join(root, "notes.txt"), "notes")
+	write(filepath.Join(outside, "secret.pdf"), "secret content")
+	if err := os.Symlink(filepath.Join(outside, "secret.pdf"), filepath.Join(root, "link.pdf")); err != nil {
+		t.Skipf("无法创建符号链接: %v", err)
+	}
+	os.Mkdir(filepath.Join(root, "dir.pdf"), 0o755)
+
+	access := FileAccess{
+		AllowedRoots:      []string{root},
+		AllowedExtensions: []string{".PDF"},
+		MaxBytes:          100,
+	}
+	for _, path := range []string{filepath.Join(root, "report.pdf"), "report.pdf"} {
+		file, err := access.open(path)
+		if err != nil {
+			t.Errorf("open(%s) failed: %v", path, err)
+			continue
+		}
+		file.Close()
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{filepath.Join(outside, "secret.pdf"), "不在允许访问的目录内"},
+		{filepath.Join(root, "..", filepath.Base(outside), "secret.pdf"), "不在允许访问的目录内"},
+		{"../" + filepath.Base(outside) + "/secret.pdf", "不在允许访问的目录内"},
+		{filepath.Join(root, "link.pdf"), "不在允许访问的目录内"},
+		{filepath.Join(root, "notes.txt"), "扩展名"},
+		{filepath.Join(root, "dir.pdf"), "不是普通文件"},
+		{filepath.Join(root, "missing.pdf"), "不存在"},
+	}
+	for _, tt := range tests {
+		file, err := access.open(tt.path)
+		if err == nil {
+			file.Close()
+			t.Errorf("open(%s) 应返回错误", tt.path)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("open(%s) = %v，期望包含 %q", tt.path, err, tt.want)
+		}
+	}
+
+	access.MaxBytes = 5
+	if _, err := access.open(filepath.Join(root, "report.pdf")); err == nil || !strings.Contains(err.Error(), "超过") {
+		t.Errorf("超过大小限制时应返回错误，实际: %v", err)
+	}
+}
+
+func TestFileAccessDirectorySwappedBeforeOpen(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("只有 Linux 能检查实际打开的文件路径")
+	}
+	root := t.TempDir()
+	outside := t.TempDir()
+	os.Mkdir(filepath.Join(root, "reports"), 0o755)
+	os.WriteFile(filepath.Join(root, "reports", "secret.pdf"), []byte("report"), 0o644)
+	os.WriteFile(filepath.Join(outside, "secret.pdf"), []byte("secret"), 0o644)
+
+	// 检查通过后把目录替换为指向目录外的符号链接
+	testHookBeforeOpen = func() {
+		os.Rename(filepath.Join(root, "reports"), filepath.Join(root, "reports.old"))
+		os.Symlink(outside, filepath.Join(root, "reports"))
+	}
+	t.Cleanup(func() { testHookBeforeOpen = nil })
+
+	access := FileAccess{AllowedRoots: []string{root}}
+	file, err := access.open(filepath.Join(root, "reports", "secret.pdf"))
+	if err == nil {
+		file.Close()
+		t.Fatal("目录被替换为符号链接后应拒绝打开")
+	}
+	if !strings.Contains(err.Error(), "不在允许访问的目录内") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestUploadFileOutsideSandbox(t *testing.T) {
+	root := t.TempDir()
+	ts := newTestServer(t, WithFileAccess(FileAccess{AllowedRoots: []string{root}}))
+
+	text, isErr := ts.call(t, "upload-file", map[string]any{
+		"webhook_key": "key",
+		"file_path":   "/etc/passwd",
+	})
+	if !isErr || !strings.Contains(text, "不在允许访问的目录内") {
+		t.Errorf("沙箱外的文件应返回错误，实际: %s", text)
+	}
+	if sent := ts.sent(); len(sent) != 0 {
+		t.Errorf("不应发送任何请求: %v", sent)
+	}
+}
+
+func TestFileAccessDeniedByDefault(t *testing.T) {
+	ts := newTestServer(t)
+
+	text, isErr := ts.call(t, "upload-file", map[string]any{
+		"webhook_key": "key",
+		"file_path":   "/etc/passwd",
+	})
+	if !isErr || !strings.Contains(text, "files.allowed_dirs") {
+		t.Errorf("未配置允许的目录时应拒绝读取本地文件，实际: %s", text)
+	}
+	if sent := ts.sent(); len(sent) != 0 {
+		t.Errorf("不应发送任何请求: %v", sent)
+	}
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"wecom-bot-server-go/internal/wecom"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// mediaCacheURITemplate 素材缓存资源的 URI 模板，按机器人名称查看，允许直接传入 webhook key 时也可使用 key
+const mediaCacheURITemplate = "wecom://media-cache/{bot}"
+
+// WithMediaCache 启用素材缓存：相同内容在有效期内重复上传时复用媒体ID，并可通过资源查看缓存内容
+func WithMediaCache(cache *wecom.MediaCache) Option {
+	return func(s *Server) {
+		s.mediaCache = cache
+		s.clientOptions = append(s.clientOptions, wecom.WithMediaCache(cache))
+	}
+}
+
+// RegisterResources 注册 MCP 资源
+func (s *Server) RegisterResources(ctx context.Context) error {
+	if len(s.botNames) > 0 {
+		s.registerBotsResource()
+	}
+	if s.mediaCache != nil {
+		s.registerMediaCacheResource()
+	}
+	return nil
+}
+
+// registerMediaCacheResource 注册素材缓存资源
+func (s *Server) registerMediaCacheResource() {
+	template := mcp.NewResourceTemplate(mediaCacheURITemplate, "media-cache",
+		mcp.WithTemplateDescription("查看指定机器人（bot 为已登记的机器人名称）已上传且未过期的素材，包括媒体ID、文件名、内容哈希和过期时间，可直接复用媒体ID发送文件消息"),
+		mcp.WithTemplateMIMEType("application/json"),
+	)
+	s.mcpServer.AddResourceTemplate(template, s.handleReadMediaCache)
+}
+
+// handleReadMediaCache 返回机器人的素材缓存列表
+func (s *Server) handleReadMediaCache(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	value := uriArgument(request.Params.Arguments["bot"])
+	args := map[string]any{"webhook_key": value}
+	if _, ok := s.bots[value]; ok {
+		args = map[string]any{"bot": value}
+	}
+	if err := s.authorize(ctx, "", args); err != nil {
+		return nil, err
+	}
+	webhookKey, err := s.resourceWebhookKey(value)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(s.mediaCache.List(webhookKey), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("序列化素材缓存失败: %w", err)
+	}
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      request.Params.URI,
+			MIMEType: "application/json",
+			Text:     string(data),
+		},
+	}, nil
+}
+
+// resourceWebhookKey 将资源 URI 中的机器人名称或 webhook key 转换为 webhook key
+func (s *Server) resourceWebhookKey(value string) (string, error) {
+	if bot, ok := s.bots[value]; ok {
+		return bot.WebhookKey, nil
+	}
+	return s.webhookKey(map[string]any{"webhook_key": value})
+}
+
+// uriArgument 取出 URI 模板变量的值，模板变量可能被解析为字符串或字符串列表
+func uriArgument(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []string:
+		if len(v) > 0 {
+			return v[0]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"wecom-bot-server-go/internal/wecom"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestMediaCacheResource(t *testing.T) {
+	cache, _ := wecom.NewMediaCache("")
+	ts := newTestServer(t, WithMediaCache(cache))
+	if err := ts.RegisterResources(context.Background()); err != nil {
+		t.Fatalf("注册资源失败: %v", err)
+	}
+
+	args := map[string]any{
+		"webhook_key":    "key",
+		"content_base64": base64.StdEncoding.EncodeToString([]byte("daily report")),
+		"filename":       "report.txt",
+	}
+	if text, isErr := ts.call(t, "upload-file", args); isErr || strings.Contains(text, "复用") {
+		t.Fatalf("首次上传结果: %s", text)
+	}
+	if text, isErr := ts.call(t, "upload-file", args); isErr || !strings.Contains(text, "复用") {
+		t.Fatalf("重复上传应复用缓存: %s", text)
+	}
+
+	raw, _ := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "resources/read",
+		"params":  map[string]any{"uri": "wecom://media-cache/key"},
+	})
+	resp, ok := ts.mcpServer.HandleMessage(context.Background(), raw).(mcp.JSONRPCResponse)
+	if !ok {
+		t.Fatal("读取资源失败")
+	}
+	result, ok := resp.Result.(mcp.ReadResourceResult)
+	if !ok || len(result.Contents) != 1 {
+		t.Fatalf("资源返回了无法识别的结果: %#v", resp.Result)
+	}
+	text, _ := result.Contents[0].(mcp.TextResourceContents)
+
+	var entries []wecom.CachedMedia
+	if err := json.Unmarshal([]byte(text.Text), &entries); err != nil {
+		t.Fatalf("解析资源内容失败: %v", err)
+	}
+	if len(entries) != 1 || entries[0].MediaID != "test-media-id" || entries[0].Filename != "report.txt" {
+		t.Errorf("entries = %+v", entries)
+	}
+}
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+
+	"wecom-bot-server-go/internal/auth"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// 策略规则的效果
+const (
+	PolicyAllow = "allow"
+	PolicyDeny  = "deny"
+)
+
+// AnonymousCaller 未启用认证（例如 stdio 模式）时调用方在策略中的名称
+const AnonymousCaller = "anonymous"
+
+// PolicyRule 一条授权规则，调用方、机器人、工具都匹配时生效。
+// 各字段支持 * 等通配符，为空时匹配任意值；Bots 为空时也匹配直接传入 webhook_key 的调用。
+type PolicyRule struct {
+	// Clients 调用方名称，即认证配置中的 name
+	Clients []string `json:"clients,omitempty" yaml:"clients,omitempty" toml:"clients,omitempty"`
+	// Bots 机器人名称
+	Bots []string `json:"bots,omitempty" yaml:"bots,omitempty" toml:"bots,omitempty"`
+	// Tools 工具名称
+	Tools []string `json:"tools,omitempty" yaml:"tools,omitempty" toml:"tools,omitempty"`
+	// Effect allow 或 deny
+	Effect string `json:"effect" yaml:"effect" toml:"effect"`
+	// Reason 拒绝时返回给调用方的说明
+	Reason string `json:"reason,omitempty" yaml:"reason,omitempty" toml:"reason,omitempty"`
+}
+
+// Policy 发送和上传工具的授权策略，按顺序匹配规则，第一条匹配的规则决定结果
+type Policy struct {
+	Rules []PolicyRule `json:"rules" yaml:"rules" toml:"rules"`
+	// Default 没有规则匹配时的效果，为空时按 deny 处理
+	Default string `json:"default" yaml:"default" toml:"default"`
+	// DryRun 只记录策略结果不拒绝调用，用于上线前验证策略
+	DryRun bool `json:"dry_run" yaml:"dry_run" toml:"dry_run"`
+}
+
+// PolicyDecision 策略评估结果
+type PolicyDecision struct {
+	Allowed bool
+	// Rule 匹配的规则序号（从 1 开始），为 0 时表示使用默认效果
+	Rule   int
+	Reason string
+}
+
+// Validate 检查规则效果和通配符格式
+func (p Policy) Validate() error {
+	switch p.Default {
+	case "", PolicyAllow, PolicyDeny:
+	default:
+		return fmt.Errorf("默认效果必须为 allow 或 deny，实际为 %q", p.Default)
+	}
+	for i, rule := range p.Rules {
+		if rule.Effect != PolicyAllow && rule.Effect != PolicyDeny {
+			return fmt.Errorf("第 %d 条规则的效果必须为 allow 或 deny，实际为 %q", i+1, rule.Effect)
+		}
+		for _, patterns := range [][]string{rule.Clients, rule.Bots, rule.Tools} {
+			for _, pattern := range patterns {
+				if _, err := path.Match(pattern, ""); err != nil {
+					return fmt.Errorf("第 %d 条规则的通配符 %q 格式错误", i+1, pattern)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Evaluate 评估调用方使用机器人调用工具是否被允许，bot 为空表示直接传入了 webhook_key
+func (p Policy) Evaluate(caller, bot, tool string) PolicyDecision {
+	for i, rule := range p.Rules {
+		if !policyMatch(rule.Clients, caller) || !policyMatch(rule.Tools, tool) {
+			continue
+		}
+		if bot == "" && len(rule.Bots) > 0 || bot != "" && !policyMatch(rule.Bots, bot) {
+			continue
+		}
+		decision := PolicyDecision{Allowed: rule.Effect == PolicyAllow, Rule: i + 1, Reason: rule.Reason}
+		if !decision.Allowed && decision.Reason == "" {
+			decision.Reason = fmt.Sprintf("被第 %d 条策略规则拒绝", i+1)
+		}
+		return decision
+	}
+	if p.Default == PolicyAllow {
+		return PolicyDecision{Allowed: true}
+	}
+	return PolicyDecision{Reason: "没有允许该调用的策略规则"}
+}
+
+// policyMatch 判断 name 是否匹配任一通配符模式，模式列表为空时总是匹配
+func policyMatch(patterns []string, name string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// WithPolicy 设置发送和上传工具的授权策略，没有规则时不做限制
+func WithPolicy(policy Policy) Option {
+	return func(s *Server) {
+		if len(policy.Rules) > 0 || policy.Default == PolicyDeny {
+			s.policy = &policy
+		}
+	}
+}
+
+// policyBot 返回调用参数中的机器人名称，直接传入的 webhook key 与已登记的机器人相同时按该机器人处理
+func (s *Server) policyBot(args map[string]any) string {
+	if name, _ := args["bot"].(string); name != "" {
+		return name
+	}
+	key, _ := args["webhook_key"].(string)
+	for _, name := range s.botNames {
+		if key != "" && s.bots[name].WebhookKey == key {
+			return name
+		}
+	}
+	return ""
+}
+
+// enforcePolicy 在工具处理函数之前评估授权策略，拒绝时返回工具错误；试运行模式下只记录日志
+func (s *Server) enforcePolicy(tool string, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	if s.policy == nil {
+		return handler
+	}
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		caller := AnonymousCaller
+		if id, ok := auth.FromContext(ctx); ok {
+			caller = id.Name
+		}
+		bot := s.policyBot(request.GetArguments())
+		decision := s.policy.Evaluate(caller, bot, tool)
+
+		attrs := []any{"caller", caller, "bot", bot, "tool", tool, "allowed", decision.Allowed, "rule", decision.Rule}
+		switch {
+		case s.policy.DryRun:
+			slog.Info("策略试运行", append(attrs, "reason", decision.Reason)...)
+		case !decision.Allowed:
+			slog.Warn("策略拒绝工具调用", append(attrs, "reason", decision.Reason)...)
+			return mcp.NewToolResultError("策略拒绝: " + decision.Reason), nil
+		}
+		return handler(ctx, request)
+	}
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"wecom-bot-server-go/internal/auth"
+)
+
+// teamPolicy CI 只能向 build-bot 发送 Markdown，客服可以使用 support-* 机器人的所有工具
+var teamPolicy = Policy{
+	Rules: []PolicyRule{
+		{Clients: []string{"ci-agent"}, Bots: []string{"build-bot"}, Tools: []string{"send-markdown"}, Effect: PolicyAllow},
+		{Clients: []string{"support-agent"}, Bots: []string{"support-*"}, Effect: PolicyAllow},
+		{Tools: []string{"upload-file"}, Effect: PolicyDeny, Reason: "禁止上传文件"},
+	},
+}
+
+func TestPolicyEvaluate(t *testing.T) {
+	tests := []struct {
+		caller, bot, tool string
+		allowed           bool
+		rule              int
+	}{
+		{"ci-agent", "build-bot", "send-markdown", true, 1},
+		{"ci-agent", "build-bot", "send-text", false, 0},
+		{"ci-agent", "support-cn", "send-markdown", false, 0},
+		{"support-agent", "support-cn", "send-file", true, 2},
+		{"support-agent", "support-cn", "upload-file", true, 2},
+		{"support-agent", "", "send-text", false, 0},
+		{"ops", "build-bot", "upload-file", false, 3},
+		{AnonymousCaller, "", "upload-file", false, 3},
+	}
+	for _, tt := range tests {
+		decision := teamPolicy.Evaluate(tt.caller, tt.bot, tt.tool)
+		if decision.Allowed != tt.allowed || decision.Rule != tt.rule {
+			t.Errorf("Evaluate(%q, %q, %q) = %+v, want allowed=%v rule=%d", tt.caller, tt.bot, tt.tool, decision, tt.allowed, tt.rule)
+		}
+		if !decision.Allowed && decision.Reason == "" {
+			t.Errorf("Evaluate(%q, %q, %q) 拒绝时缺少原因", tt.caller, tt.bot, tt.tool)
+		}
+	}
+
+	if decision := (Policy{Default: PolicyAllow}).Evaluate("any", "", "send-text"); !decision.Allowed {
+		t.Errorf("默认允许时 = %+v", decision)
+	}
+}
+
+func TestPolicyValidate(t *testing.T) {
+	if err := teamPolicy.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	invalid := []Policy{
+		{Default: "maybe"},
+		{Rules: []PolicyRule{{Effect: "permit"}}},
+		{Rules: []PolicyRule{{Effect: PolicyAllow, Bots: []string{"["}}}},
+	}
+	for _, p := range invalid {
+		if err := p.Validate(); err == nil {
+			t.Errorf("Validate(%+v) 应返回错误", p)
+		}
+	}
+}
+
+func TestPolicyEnforced(t *testing.T) {
+	ts := newTestServer(t,
+		WithBots(Bot{Name: "build-bot", WebhookKey: "build-key"}, Bot{Name: "support-cn", WebhookKey: "support-key"}),
+		WithPolicy(teamPolicy),
+	)
+	ci := auth.NewContext(context.Background(), &auth.Identity{Name: "ci-agent"})
+
+	if text, isErr := ts.callContext(ci, t, "send-markdown", map[string]any{"bot": "build-bot", "content": "构建成功"}); isErr {
+		t.Fatalf("发送失败: %s", text)
+	}
+	// 直接传入已登记机器人的 webhook key 时按该机器人评估
+	if text, isErr := ts.callContext(ci, t, "send-markdown", map[string]any{"webhook_key": "build-key", "content": "构建成功"}); isErr {
+		t.Fatalf("发送失败: %s", text)
+	}
+
+	text, isErr := ts.callContext(ci, t, "send-text", map[string]any{"bot": "build-bot", "content": "hi"})
+	if !isErr || !strings.Contains(text, "策略拒绝") {
+		t.Errorf("send-text = %s", text)
+	}
+	text, isErr = ts.call(t, "upload-file", map[string]any{"bot": "support-cn", "content_base64": "aGVsbG8gd29ybGQ=", "filename": "a.txt"})
+	if !isErr || !strings.Contains(text, "禁止上传文件") {
+		t.Errorf("upload-file = %s", text)
+	}
+	if len(ts.keys) != 2 {
+		t.Errorf("被拒绝的调用不应发送消息，keys = %v", ts.keys)
+	}
+}
+
+func TestPolicyDryRun(t *testing.T) {
+	policy := teamPolicy
+	policy.DryRun = true
+	ts := newTestServer(t, WithBots(Bot{Name: "build-bot", WebhookKey: "build-key"}), WithPolicy(policy))
+
+	if text, isErr := ts.call(t, "send-text", map[string]any{"bot": "build-bot", "content": "hi"}); isErr {
+		t.Errorf("试运行模式下不应拒绝调用: %s", text)
+	}
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimit 单个机器人的发送频率限制
+type RateLimit struct {
+	// Limit 时间窗口内允许发送的消息数，小于等于 0 表示不限制
+	Limit int
+	// Window 时间窗口长度
+	Window time.Duration
+	// Wait 超出限制时是否在工具调用截止时间内排队等待，为 false 时立即失败
+	Wait bool
+}
+
+// DefaultRateLimit 企业微信群机器人默认限制：每个机器人每分钟最多 20 条消息
+var DefaultRateLimit = RateLimit{
+	Limit:  20,
+	Window: time.Minute,
+	Wait:   true,
+}
+
+// WithRateLimit 设置所有机器人的默认发送频率限制
+func WithRateLimit(limit RateLimit) Option {
+	return func(s *Server) {
+		s.limiter.defaultLimit = limit
+	}
+}
+
+// WithKeyRateLimit 为指定 webhook key 单独设置发送频率限制
+func WithKeyRateLimit(webhookKey string, limit RateLimit) Option {
+	return func(s *Server) {
+		s.limiter.overrides[webhookKey] = limit
+	}
+}
+
+// RateLimitError 超出发送频率限制时返回的错误
+type RateLimitError struct {
+	Limit      RateLimit
+	RetryAfter time.Duration
+}
+
+// Error 实现 error 接口
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("已触发机器人发送频率限制（每 %s 最多 %d 条），请在 %d 秒后重试",
+		e.Limit.Window, e.Limit.Limit, int(math.Ceil(e.RetryAfter.Seconds())))
+}
+
+// sweepInterval 清理所有 webhook key 过期发送记录的最小间隔
+const sweepInterval = time.Minute
+
+// rateLimiter 按 webhook key 统计发送次数的滑动窗口限流器，由所有工具共享
+type rateLimiter struct {
+	mu           sync.Mutex
+	defaultLimit RateLimit
+	overrides    map[string]RateLimit
+	sent         map[string][]time.Time
+	// swept 上次清理过期记录的时间，允许直接传入 webhook key 时调用方可以使用任意多的 key
+	swept time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{
+		defaultLimit: DefaultRateLimit,
+		overrides:    make(map[string]RateLimit),
+		sent:         make(map[string][]time.Time),
+	}
+}
+
+// limitFor 返回 webhook key 对应的限流配置
+func (l *rateLimiter) limitFor(webhookKey string) RateLimit {
+	if limit, ok := l.overrides[webhookKey]; ok {
+		return limit
+	}
+	return l.defaultLimit
+}
+
+// reserve 尝试占用一个发送名额，失败时返回需要等待的时间
+func (l *rateLimiter) reserve(webhookKey string, now time.Time) (RateLimit, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.swept) >= sweepInterval {
+		l.sweep(now)
+	}
+
+	limit := l.limitFor(webhookKey)
+	if limit.Limit <= 0 || limit.Window <= 0 {
+		return limit, 0
+	}
+
+	// 清理窗口外的发送记录
+	sent := l.sent[webhookKey]
+	cutoff := now.Add(-limit.Window)
+	i := 0
+	for i < len(sent) && !sent[i].After(cutoff) {
+		i++
+	}
+	sent = sent[i:]
+
+	if len(sent) < limit.Limit {
+		l.sent[webhookKey] = append(sent, now)
+		return limit, 0
+	}
+	l.sent[webhookKey] = sent
+	return limit, sent[len(sent)-limit.Limit].Add(limit.Window).Sub(now)
+}
+
+// sweep 删除最近一次发送已在窗口外的 webhook key，调用方需持有锁
+func (l *rateLimiter) sweep(now time.Time) {
+	l.swept = now
+	for webhookKey, sent := range l.sent {
+		if len(sent) == 0 || !sent[len(sent)-1].After(now.Add(-l.limitFor(webhookKey).Window)) {
+			delete(l.sent, webhookKey)
+		}
+	}
+}
+
+// Wait 占用一个发送名额，按配置排队等待或立即返回 *RateLimitError
+func (l *rateLimiter) Wait(ctx context.Context, webhookKey string) error {
+	for {
+		limit, wait := l.reserve(webhookKey, time.Now())
+		if wait <= 0 {
+			return nil
+		}
+
+		if !limit.Wait {
+			return &RateLimitError{Limit: limit, RetryAfter: wait}
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return &RateLimitError{Limit: limit, RetryAfter: wait}
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterReserve(t *testing.T) {
+	l := newRateLimiter()
+	l.defaultLimit = RateLimit{Limit: 2, Window: time.Minute}
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if _, wait := l.reserve("key", now); wait != 0 {
+			t.Fatalf("第 %d 条消息不应被限流", i+1)
+		}
+	}
+	if _, wait := l.reserve("key", now.Add(10*time.Second)); wait != 50*time.Second {
+		t.Errorf("wait = %v, want 50s", wait)
+	}
+	if _, wait := l.reserve("other", now); wait != 0 {
+		t.Errorf("不同 webhook key 应分别计数")
+	}
+	if _, wait := l.reserve("key", now.Add(time.Minute+time.Second)); wait != 0 {
+		t.Errorf("窗口过期后应恢复发送")
+	}
+}
+
+func TestRateLimiterSweep(t *testing.T) {
+	l := newRateLimiter()
+	l.defaultLimit = RateLimit{Limit: 2, Window: time.Minute}
+	now := time.Now()
+
+	for _, key := range []string{"a", "b", "c"} {
+		l.reserve(key, now)
+	}
+	if len(l.sent) != 3 {
+		t.Fatalf("len(sent) = %d, want 3", len(l.sent))
+	}
+
+	// 窗口过后不再使用的 key 被清理
+	l.reserve("d", now.Add(time.Minute+sweepInterval))
+	if len(l.sent) != 1 {
+		t.Errorf("窗口过后 len(sent) = %d, want 1", len(l.sent))
+	}
+}
+
+func TestRateLimiterFailFast(t *testing.T) {
+	l := newRateLimiter()
+	l.overrides["key"] = RateLimit{Limit: 1, Window: time.Minute, Wait: false}
+
+	if err := l.Wait(context.Background(), "key"); err != nil {
+		t.Fatalf("首条消息不应被限流: %v", err)
+	}
+	var rlErr *RateLimitError
+	if err := l.Wait(context.Background(), "key"); !errors.As(err, &rlErr) {
+		t.Fatalf("期望返回 *RateLimitError，实际: %v", err)
+	}
+	if rlErr.RetryAfter <= 0 || rlErr.RetryAfter > time.Minute {
+		t.Errorf("RetryAfter = %v", rlErr.RetryAfter)
+	}
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	l := newRateLimiter()
+	l.defaultLimit = RateLimit{Limit: 1, Window: 50 * time.Millisecond, Wait: true}
+
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		if err := l.Wait(context.Background(), "key"); err != nil {
+			t.Fatalf("Wait failed: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("第二条消息应等待窗口过期，实际仅等待 %v", elapsed)
+	}
+
+	// 截止时间不足以等待时立即失败
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	var rlErr *RateLimitError
+	if err := l.Wait(ctx, "key"); !errors.As(err, &rlErr) {
+		t.Fatalf("期望返回 *RateLimitError，实际: %v", err)
+	}
+}
@@ -1,8 +1,15 @@
 package server
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"wecom-bot-server-go/internal/wecom"
 
@@ -12,16 +19,67 @@ import (
 
 // Server MCP服务器包装器
 type Server struct {
-	mcpServer *server.MCPServer
+	mcpServer     *server.MCPServer
+	clientOptions []wecom.Option
+	limiter       *rateLimiter
+	splitInterval time.Duration
+	mediaCache    *wecom.MediaCache
+	fileAccess    FileAccess
+
+	// bots 按名称登记的机器人，botNames 保持登记顺序
+	bots         map[string]Bot
+	botNames     []string
+	allowRawKeys bool
+
+	// policy 发送和上传工具的授权策略，为 nil 时不限制
+	policy *Policy
+
+	// calls 进行中的工具调用，用于关闭时等待
+	calls *calls
 }
 
-// New 创建新的服务器实例
-func New(mcpServer *server.MCPServer) *Server {
-	return &Server{
-		mcpServer: mcpServer,
+// Option 服务器配置选项
+type Option func(*Server)
+
+// WithClientOptions 设置创建企业微信客户端时使用的选项，例如接口地址和超时
+func WithClientOptions(opts ...wecom.Option) Option {
+	return func(s *Server) {
+		s.clientOptions = append(s.clientOptions, opts...)
+	}
+}
+
+// DefaultSplitInterval 超长消息拆分发送时相邻消息的默认间隔
+const DefaultSplitInterval = 500 * time.Millisecond
+
+// WithSplitInterval 设置超长消息拆分发送时相邻消息的间隔
+func WithSplitInterval(interval time.Duration) Option {
+	return func(s *Server) {
+		s.splitInterval = interval
 	}
 }
 
+// New 创建新的服务器实例
+func New(mcpServer *server.MCPServer, opts ...Option) *Server {
+	s := &Server{
+		mcpServer:     mcpServer,
+		limiter:       newRateLimiter(),
+		splitInterval: DefaultSplitInterval,
+		bots:          make(map[string]Bot),
+		allowRawKeys:  true,
+		calls:         newCalls(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.applyBotRateLimits()
+	return s
+}
+
+// newClient 使用服务器配置创建企业微信客户端
+func (s *Server) newClient(webhookKey string) *wecom.Client {
+	return wecom.NewClient(webhookKey, s.clientOptions...)
+}
+
 // RegisterTools 注册所有工具
 func (s *Server) RegisterTools(ctx context.Context) error {
 	// 注册发送文本消息工具
@@ -49,11 +107,36 @@ func (s *Server) RegisterTools(ctx context.Context) error {
 		return err
 	}
 
+	// 注册发送文本通知模板卡片工具
+	if err := s.registerSendTextNoticeCardTool(); err != nil {
+		return err
+	}
+
+	// 注册发送图文展示模板卡片工具
+	if err := s.registerSendNewsNoticeCardTool(); err != nil {
+		return err
+	}
+
 	// 注册上传文件工具
 	if err := s.registerUploadFileTool(); err != nil {
 		return err
 	}
 
+	// 注册发送文件消息工具
+	if err := s.registerSendFileTool(); err != nil {
+		return err
+	}
+
+	// 注册发送语音消息工具
+	if err := s.registerSendVoiceTool(); err != nil {
+		return err
+	}
+
+	// 注册列出机器人工具
+	if err := s.registerListBotsTool(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -61,10 +144,6 @@ func (s *Server) RegisterTools(ctx context.Context) error {
 func (s *Server) registerSendTextTool() error {
 	tool := mcp.NewTool("send-text",
 		mcp.WithDescription("发送文本消息到企业微信群"),
-		mcp.WithString("webhook_key",
-			mcp.Required(),
-			mcp.Description("企业微信机器人的Webhook Key"),
-		),
 		mcp.WithString("content",
 			mcp.Required(),
 			mcp.Description("要发送的文本内容"),
@@ -75,9 +154,12 @@ func (s *Server) registerSendTextTool() error {
 		mcp.WithString("mentioned_mobile_list",
 			mcp.Description("要@的手机号列表，多个用户用逗号分隔，例如：@xiaoyang,@wike"),
 		),
+		mcp.WithBoolean("split",
+			mcp.Description("内容超过 2048 字节时是否自动按段落拆分为多条消息依次发送，每条末尾带 (1/3) 形式的编号"),
+		),
 	)
 
-	s.mcpServer.AddTool(tool, s.handleSendText)
+	s.addTool(tool, s.handleSendText)
 	return nil
 }
 
@@ -85,67 +167,71 @@ func (s *Server) registerSendTextTool() error {
 func (s *Server) registerSendMarkdownTool() error {
 	tool := mcp.NewTool("send-markdown",
 		mcp.WithDescription("发送Markdown消息到企业微信群"),
-		mcp.WithString("webhook_key",
-			mcp.Required(),
-			mcp.Description("企业微信机器人的Webhook Key"),
-		),
 		mcp.WithString("content",
 			mcp.Required(),
 			mcp.Description("要发送的Markdown内容"),
 		),
+		mcp.WithBoolean("split",
+			mcp.Description("内容超过 4096 字节时是否自动按段落和代码块拆分为多条消息依次发送，每条末尾带 (1/3) 形式的编号"),
+		),
 	)
 
-	s.mcpServer.AddTool(tool, s.handleSendMarkdown)
+	s.addTool(tool, s.handleSendMarkdown)
 	return nil
 }
 
 // registerSendImageTool 注册发送图片消息工具
 func (s *Server) registerSendImageTool() error {
 	tool := mcp.NewTool("send-image",
-		mcp.WithDescription("发送图片消息到企业微信群"),
-		mcp.WithString("webhook_key",
-			mcp.Required(),
-			mcp.Description("企业微信机器人的Webhook Key"),
+		mcp.WithDescription("发送图片消息到企业微信群，图片可通过 file_path、image_url 或 base64_data 提供其中之一；GIF、WebP、BMP 或超过 2MB 的图片会自动转换为 JPG/PNG 并压缩"),
+		mcp.WithString("file_path",
+			mcp.Description("本地图片文件路径"),
+		),
+		mcp.WithString("image_url",
+			mcp.Description("网络图片的 http/https 地址，由服务器下载后发送，不能是内网地址"),
 		),
 		mcp.WithString("base64_data",
-			mcp.Required(),
 			mcp.Description("Base64编码的图片数据"),
 		),
 		mcp.WithString("md5",
-			mcp.Required(),
-			mcp.Description("图片的MD5哈希值"),
+			mcp.Description("图片的MD5哈希值，仅与 base64_data 一起使用，省略时自动计算"),
 		),
 	)
 
-	s.mcpServer.AddTool(tool, s.handleSendImage)
+	s.addTool(tool, s.handleSendImage)
 	return nil
 }
 
 // registerSendNewsTool 注册发送图文消息工具
 func (s *Server) registerSendNewsTool() error {
 	tool := mcp.NewTool("send-news",
-		mcp.WithDescription("发送图文消息到企业微信群"),
-		mcp.WithString("webhook_key",
-			mcp.Required(),
-			mcp.Description("企业微信机器人的Webhook Key"),
+		mcp.WithDescription("发送图文消息到企业微信群，可通过 articles 一次发送 1~8 篇文章，或使用 title/url 等参数发送单篇文章"),
+		mcp.WithArray("articles",
+			mcp.Description("图文消息文章列表，1~8 篇，提供时忽略 title、description、url、picurl 参数"),
+			mcp.Items(objectSchema(map[string]any{
+				"title":       stringProp("文章标题，不超过128个字节"),
+				"description": stringProp("文章描述，不超过512个字节"),
+				"url":         stringProp("点击后跳转的链接"),
+				"picurl":      stringProp("图片链接，大图 1068*455，小图 150*150"),
+			}, "title", "url")),
+			mcp.MinItems(1),
+			mcp.MaxItems(wecom.MaxNewsArticles),
 		),
 		mcp.WithString("title",
-			mcp.Required(),
-			mcp.Description("图文消息标题"),
+			mcp.Description("单篇图文消息标题"),
 		),
 		mcp.WithString("description",
-			mcp.Description("图文消息描述"),
+			mcp.Description("单篇图文消息描述"),
 		),
 		mcp.WithString("url",
-			mcp.Required(),
-			mcp.Description("图文消息链接"),
+			mcp.Description("单篇图文消息链接"),
 		),
 		mcp.WithString("picurl",
-			mcp.Description("图文消息图片链接"),
+			mcp.Description("单篇图文消息图片链接"),
 		),
 	)
 
-	s.mcpServer.AddTool(tool, s.handleSendNews)
+	s.addTool(tool, s.handleSendNews)
 	return nil
 }
 
@@ -153,10 +239,6 @@ func (s *Server) registerSendNewsTool() error {
 func (s *Server) registerSendTemplateCardTool() error {
 	tool := mcp.NewTool("send-template-card",
 		mcp.WithDescription("发送模板卡片消息到企业微信群"),
-		mcp.WithString("webhook_key",
-			mcp.Required(),
-			mcp.Description("企业微信机器人的Webhook Key"),
-		),
 		mcp.WithString("card_type",
 			mcp.Required(),
 			mcp.Description("模板卡片类型"),
@@ -183,25 +265,65 @@ func (s *Server) registerSendTemplateCardTool() error {
 		),
 	)
 
-	s.mcpServer.AddTool(tool, s.handleSendTemplateCard)
+	s.addTool(tool, s.handleSendTemplateCard)
 	return nil
 }
 
 // registerUploadFileTool 注册上传文件工具
 func (s *Server) registerUploadFileTool() error {
 	tool := mcp.NewTool("upload-file",
-		mcp.WithDescription("上传文件到企业微信"),
-		mcp.WithString("webhook_key",
-			mcp.Required(),
-			mcp.Description("企业微信机器人的Webhook Key"),
+		mcp.WithDescription("上传文件到企业微信，file_path 和 content_base64 二选一；远程调用时可用 content_base64 直接上传自行生成的文件"),
+		mcp.WithString("file_path",
+			mcp.Description("要上传的服务器本地文件路径"),
+		),
+		mcp.WithString("content_base64",
+			mcp.Description("Base64编码的文件内容"),
 		),
+		mcp.WithString("filename",
+			mcp.Description("群聊中显示的文件名，使用 content_base64 时建议填写"),
+		),
+		mcp.WithString("media_type",
+			mcp.Description("素材类型：file（普通文件，默认）或 voice（AMR 格式语音，不超过 2MB、60 秒）"),
+			mcp.Enum(string(wecom.MediaTypeFile), string(wecom.MediaTypeVoice)),
+		),
+	)
+
+	s.addTool(tool, s.handleUploadFile)
+	return nil
+}
+
+// registerSendFileTool 注册发送文件消息工具
+func (s *Server) registerSendFileTool() error {
+	tool := mcp.NewTool("send-file",
+		mcp.WithDescription("上传文件并以文件消息发送到企业微信群，file_path 和 content_base64 二选一"),
 		mcp.WithString("file_path",
-			mcp.Required(),
-			mcp.Description("要上传的文件路径"),
+			mcp.Description("要发送的服务器本地文件路径"),
+		),
+		mcp.WithString("content_base64",
+			mcp.Description("Base64编码的文件内容"),
+		),
+		mcp.WithString("filename",
+			mcp.Description("群聊中显示的文件名，使用 content_base64 时建议填写"),
+		),
+	)
+
+	s.addTool(tool, s.handleSendFile)
+	return nil
+}
+
+// registerSendVoiceTool 注册发送语音消息工具
+func (s *Server) registerSendVoiceTool() error {
+	tool := mcp.NewTool("send-voice",
+		mcp.WithDescription("上传 AMR 格式语音（不超过 2MB、60 秒）并以语音消息发送到企业微信群，file_path 和 content_base64 二选一"),
+		mcp.WithString("file_path",
+			mcp.Description("要发送的服务器本地语音文件路径"),
+		),
+		mcp.WithString("content_base64",
+			mcp.Description("Base64编码的 AMR 语音内容"),
 		),
 	)
 
-	s.mcpServer.AddTool(tool, s.handleUploadFile)
+	s.addTool(tool, s.handleSendVoice)
 	return nil
 }
 
@@ -209,9 +331,9 @@ func (s *Server) registerUploadFileTool() error {
 func (s *Server) handleSendText(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	args := request.GetArguments()
 
-	webhookKey, ok := args["webhook_key"].(string)
-	if !ok || webhookKey == "" {
-		return mcp.NewToolResultError("webhook_key参数必须是非空字符串"), nil
+	webhookKey, err := s.webhookKey(args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
 	}
 
 	content, ok := args["content"].(string)
@@ -235,23 +357,34 @@ func (s *Server) handleSendText(ctx context.Context, request mcp.CallToolRequest
 		}
 	}
 
-	// 动态创建wecom客户端
-	wecomClient := wecom.NewClient(webhookKey)
-	err := wecomClient.SendText(content, mentionedList, mentionedMobileList)
-	if err != nil {
-		return mcp.NewToolResultError("发送文本消息失败: " + err.Error()), nil
+	if split, _ := args["split"].(bool); split {
+		// 提醒放在最后一条，保证被@的成员收到通知时内容已经完整
+		parts := wecom.SplitText(content)
+		msgs := make([]wecom.Message, len(parts))
+		for i, part := range parts {
+			msgs[i] = &wecom.TextMessage{Content: part}
+		}
+		last := msgs[len(msgs)-1].(*wecom.TextMessage)
+		last.MentionedList = mentionedList
+		last.MentionedMobileList = mentionedMobileList
+		return s.sendParts(ctx, webhookKey, msgs, "发送文本消息失败", "文本消息发送成功"), nil
 	}
 
-	return mcp.NewToolResultText("文本消息发送成功"), nil
+	msg := &wecom.TextMessage{
+		Content:             content,
+		MentionedList:       mentionedList,
+		MentionedMobileList: mentionedMobileList,
+	}
+	return s.sendMessage(ctx, webhookKey, msg, "发送文本消息失败", "文本消息发送成功"), nil
 }
 
 // handleSendMarkdown 处理发送Markdown消息
 func (s *Server) handleSendMarkdown(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	args := request.GetArguments()
 
-	webhookKey, ok := args["webhook_key"].(string)
-	if !ok || webhookKey == "" {
-		return mcp.NewToolResultError("webhook_key参数必须是非空字符串"), nil
+	webhookKey, err := s.webhookKey(args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
 	}
 
 	content, ok := args["content"].(string)
@@ -259,101 +392,119 @@ func (s *Server) handleSendMarkdown(ctx context.Context, request mcp.CallToolReq
 		return mcp.NewToolResultError("content参数必须是字符串"), nil
 	}
 
-	// 动态创建wecom客户端
-	wecomClient := wecom.NewClient(webhookKey)
-	err := wecomClient.SendMarkdown(content)
-	if err != nil {
-		return mcp.NewToolResultError("发送Markdown消息失败: " + err.Error()), nil
+	if split, _ := args["split"].(bool); split {
+		parts := wecom.SplitMarkdown(content)
+		msgs := make([]wecom.Message, len(parts))
+		for i, part := range parts {
+			msgs[i] = &wecom.MarkdownMessage{Content: part}
+		}
+		return s.sendParts(ctx, webhookKey, msgs, "发送Markdown消息失败", "Markdown消息发送成功"), nil
 	}
 
-	return mcp.NewToolResultText("Markdown消息发送成功"), nil
+	msg := &wecom.MarkdownMessage{Content: content}
+	return s.sendMessage(ctx, webhookKey, msg, "发送Markdown消息失败", "Markdown消息发送成功"), nil
 }
 
 // handleSendImage 处理发送图片消息
 func (s *Server) handleSendImage(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	args := request.GetArguments()
 
-	webhookKey, ok := args["webhook_key"].(string)
-	if !ok || webhookKey == "" {
-		return mcp.NewToolResultError("webhook_key参数必须是非空字符串"), nil
+	webhookKey, err := s.webhookKey(args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
 	}
 
-	base64Data, ok := args["base64_data"].(string)
-	if !ok {
-		return mcp.NewToolResultError("base64_data参数必须是字符串"), nil
-	}
+	filePath, _ := args["file_path"].(string)
+	imageURL, _ := args["image_url"].(string)
+	base64Data, _ := args["base64_data"].(string)
+	md5Hash, _ := args["md5"].(string)
 
-	md5Hash, ok := args["md5"].(string)
-	if !ok {
-		return mcp.NewToolResultError("md5参数必须是字符串"), nil
+	// 同时提供 Base64 和 MD5 时按原样发送，兼容预先计算好的调用方
+	if base64Data != "" && md5Hash != "" {
+		msg := &wecom.ImageMessage{Base64: base64Data, MD5: md5Hash}
+		return s.sendMessage(ctx, webhookKey, msg, "发送图片消息失败", "图片消息发送成功"), nil
 	}
 
-	// 动态创建wecom客户端
-	wecomClient := wecom.NewClient(webhookKey)
-	err := wecomClient.SendImage(base64Data, md5Hash)
+	var data []byte
+	switch {
+	case filePath != "":
+		var file *os.File
+		file, err = s.fileAccess.open(filePath)
+		if err == nil {
+			data, err = wecom.ReadImage(file)
+			file.Close()
+		}
+	case imageURL != "":
+		data, err = s.newClient(webhookKey).FetchImage(ctx, imageURL)
+	case base64Data != "":
+		data, err = base64.StdEncoding.DecodeString(base64Data)
+		if err != nil {
+			return mcp.NewToolResultError("base64_data参数不是合法的Base64编码: " + err.Error()), nil
+		}
+	default:
+		return mcp.NewToolResultError("file_path、image_url和base64_data参数必须提供其中之一"), nil
+	}
 	if err != nil {
-		return mcp.NewToolResultError("发送图片消息失败: " + err.Error()), nil
+		return toolError("读取图片失败", err, nil), nil
 	}
 
-	return mcp.NewToolResultText("图片消息发送成功"), nil
-}
-
-// handleSendNews 处理发送图文消息
-func (s *Server) handleSendNews(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	args := request.GetArguments()
-
-	webhookKey, ok := args["webhook_key"].(string)
-	if !ok || webhookKey == "" {
-		return mcp.NewToolResultError("webhook_key参数必须是非空字符串"), nil
+	// 转换格式并压缩到 2MB 以内
+	prepared, err := wecom.PrepareImage(data)
+	if err != nil {
+		return toolError("处理图片失败", err, nil), nil
 	}
-
-	title, ok := args["title"].(string)
-	if !ok {
-		return mcp.NewToolResultError("title参数必须是字符串"), nil
+	msg, err := wecom.NewImageMessage(prepared.Data)
+	if err != nil {
+		return toolError("发送图片消息失败", err, nil), nil
 	}
+	return s.sendMessage(ctx, webhookKey, msg, "发送图片消息失败", "图片消息发送成功，"+describeImage(prepared)), nil
+}
 
-	url, ok := args["url"].(string)
-	if !ok {
-		return mcp.NewToolResultError("url参数必须是字符串"), nil
+// describeImage 描述发送的图片尺寸和大小，经过转换时同时说明原图信息
+func describeImage(p *wecom.PreparedImage) string {
+	desc := fmt.Sprintf("%s %dx%d，%d 字节", strings.ToUpper(p.Format), p.Width, p.Height, len(p.Data))
+	if p.Converted() {
+		desc += fmt.Sprintf("（原图 %s %dx%d，%d 字节，已自动转换）",
+			strings.ToUpper(p.SourceFormat), p.SourceWidth, p.SourceHeight, p.SourceSize)
 	}
+	return desc
+}
 
-	description := ""
-	if desc, ok := args["description"].(string); ok {
-		description = desc
+// handleSendNews 处理发送图文消息
+func (s *Server) handleSendNews(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	webhookKey, err := s.webhookKey(request.GetArguments())
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
 	}
 
-	picurl := ""
-	if pic, ok := args["picurl"].(string); ok {
-		picurl = pic
+	var args struct {
+		Articles []wecom.NewsArticle `json:"articles"`
+		wecom.NewsArticle
 	}
-
-	// 创建文章数组
-	articles := []wecom.NewsArticle{
-		{
-			Title:       title,
-			Description: description,
-			URL:         url,
-			PicURL:      picurl,
-		},
+	if err := request.BindArguments(&args); err != nil {
+		return mcp.NewToolResultError("解析参数失败: " + err.Error()), nil
 	}
 
-	// 动态创建wecom客户端
-	wecomClient := wecom.NewClient(webhookKey)
-	err := wecomClient.SendNews(articles)
-	if err != nil {
-		return mcp.NewToolResultError("发送图文消息失败: " + err.Error()), nil
+	// 未提供文章列表时使用单篇文章参数
+	articles := args.Articles
+	if len(articles) == 0 {
+		if args.Title == "" && args.URL == "" {
+			return mcp.NewToolResultError("articles参数与title、url参数必须提供其中之一"), nil
+		}
+		articles = []wecom.NewsArticle{args.NewsArticle}
 	}
 
-	return mcp.NewToolResultText("图文消息发送成功"), nil
+	msg := &wecom.NewsMessage{Articles: articles}
+	return s.sendMessage(ctx, webhookKey, msg, "发送图文消息失败", fmt.Sprintf("图文消息发送成功，共 %d 篇文章", len(articles))), nil
 }
 
 // handleSendTemplateCard 处理发送模板卡片消息
 func (s *Server) handleSendTemplateCard(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	args := request.GetArguments()
 
-	webhookKey, ok := args["webhook_key"].(string)
-	if !ok || webhookKey == "" {
-		return mcp.NewToolResultError("webhook_key参数必须是非空字符串"), nil
+	webhookKey, err := s.webhookKey(args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
 	}
 
 	cardType, ok := args["card_type"].(string)
@@ -401,36 +552,216 @@ func (s *Server) handleSendTemplateCard(ctx context.Context, request mcp.CallToo
 		CardActionPagePath: cardActionPagePath,
 	}
 
-	// 动态创建wecom客户端
-	wecomClient := wecom.NewClient(webhookKey)
-	err := wecomClient.SendTemplateCard(params)
+	msg := params.Message()
+	return s.sendMessage(ctx, webhookKey, msg, "发送模板卡片消息失败", "模板卡片消息发送成功"), nil
+}
+
+// handleUploadFile 处理上传文件
+func (s *Server) handleUploadFile(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+
+	webhookKey, err := s.webhookKey(args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	mediaTypeStr, _ := args["media_type"].(string)
+	mediaType, err := wecom.ParseMediaType(mediaTypeStr)
 	if err != nil {
-		return mcp.NewToolResultError("发送模板卡片消息失败: " + err.Error()), nil
+		return mcp.NewToolResultError(err.Error()), nil
 	}
 
-	return mcp.NewToolResultText("模板卡片消息发送成功"), nil
+	defaultFilename := "file"
+	if mediaType == wecom.MediaTypeVoice {
+		defaultFilename = "voice.amr"
+	}
+	stats := &wecom.RequestStats{}
+	media, failure := s.uploadMedia(ctx, webhookKey, args, mediaType, defaultFilename, stats)
+	if failure != nil {
+		return failure, nil
+	}
+
+	return toolResult("文件上传成功，"+describeMedia(media), stats), nil
 }
 
-// handleUploadFile 处理上传文件
-func (s *Server) handleUploadFile(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+// handleSendFile 处理上传并发送文件消息
+func (s *Server) handleSendFile(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return s.uploadAndSend(ctx, request, wecom.MediaTypeFile, "file"), nil
+}
+
+// handleSendVoice 处理上传并发送语音消息
+func (s *Server) handleSendVoice(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return s.uploadAndSend(ctx, request, wecom.MediaTypeVoice, "voice.amr"), nil
+}
+
+// uploadAndSend 按 file_path 或 content_base64 参数上传素材，再发送对应类型的消息
+func (s *Server) uploadAndSend(ctx context.Context, request mcp.CallToolRequest, mediaType wecom.MediaType, defaultFilename string) *mcp.CallToolResult {
 	args := request.GetArguments()
 
-	webhookKey, ok := args["webhook_key"].(string)
-	if !ok || webhookKey == "" {
-		return mcp.NewToolResultError("webhook_key参数必须是非空字符串"), nil
+	webhookKey, err := s.webhookKey(args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error())
+	}
+
+	media, failure := s.uploadMedia(ctx, webhookKey, args, mediaType, defaultFilename, &wecom.RequestStats{})
+	if failure != nil {
+		return failure
 	}
 
-	filePath, ok := args["file_path"].(string)
-	if !ok {
-		return mcp.NewToolResultError("file_path参数必须是字符串"), nil
+	if mediaType == wecom.MediaTypeVoice {
+		msg := &wecom.VoiceMessage{MediaID: media.ID}
+		return s.sendMessage(ctx, webhookKey, msg, "发送语音消息失败", "语音消息发送成功，"+describeMedia(media))
 	}
+	msg := &wecom.FileMessage{MediaID: media.ID}
+	return s.sendMessage(ctx, webhookKey, msg, "发送文件消息失败", "文件消息发送成功，"+describeMedia(media))
+}
+
+// uploadMedia 按 file_path 或 content_base64 参数上传素材，失败时返回对应的工具结果，
+// stats 记录上传的实际请求次数
+func (s *Server) uploadMedia(ctx context.Context, webhookKey string, args map[string]any, mediaType wecom.MediaType, defaultFilename string, stats *wecom.RequestStats) (*wecom.Media, *mcp.CallToolResult) {
+	filePath, _ := args["file_path"].(string)
+	contentBase64, _ := args["content_base64"].(string)
+	filename, _ := args["filename"].(string)
 
 	// 动态创建wecom客户端
-	wecomClient := wecom.NewClient(webhookKey)
-	mediaID, err := wecomClient.UploadFile(filePath)
+	ctx = wecom.ContextWithStats(ctx, stats)
+	wecomClient := s.newClient(webhookKey)
+
+	var media *wecom.Media
+	var err error
+	switch {
+	case filePath != "" && contentBase64 != "":
+		return nil, mcp.NewToolResultError("file_path和content_base64参数只能提供其中之一")
+	case filePath != "":
+		file, openErr := s.fileAccess.open(filePath)
+		if openErr != nil {
+			return nil, toolError("读取文件失败", openErr, nil)
+		}
+		defer file.Close()
+		media, err = wecomClient.UploadReader(ctx, filepath.Base(filePath), file, mediaType)
+	case contentBase64 != "":
+		if filename == "" {
+			filename = defaultFilename
+		}
+		data, decodeErr := base64.StdEncoding.DecodeString(contentBase64)
+		if decodeErr != nil {
+			return nil, mcp.NewToolResultError("content_base64参数不是合法的Base64编码: " + decodeErr.Error())
+		}
+		media, err = wecomClient.UploadReader(ctx, filename, bytes.NewReader(data), mediaType)
+	default:
+		return nil, mcp.NewToolResultError("file_path和content_base64参数必须提供其中之一")
+	}
 	if err != nil {
-		return mcp.NewToolResultError("上传文件失败: " + err.Error()), nil
+		return nil, toolError("上传文件失败", err, stats)
+	}
+	return media, nil
+}
+
+// sendMessage 校验消息、经过限流后发送，并将结果转换为工具结果
+func (s *Server) sendMessage(ctx context.Context, webhookKey string, msg wecom.Message, failPrefix, successMsg string) *mcp.CallToolResult {
+	// 先校验消息，避免无效消息占用发送名额
+	if err := msg.Validate(); err != nil {
+		return toolError(failPrefix, err, nil)
+	}
+
+	stats := &wecom.RequestStats{}
+	if err := s.send(ctx, webhookKey, msg, stats); err != nil {
+		return toolError(failPrefix, err, stats)
+	}
+
+	return toolResult(successMsg, stats)
+}
+
+// sendParts 按顺序发送拆分后的多条消息，相邻消息之间间隔 splitInterval
+func (s *Server) sendParts(ctx context.Context, webhookKey string, msgs []wecom.Message, failPrefix, successMsg string) *mcp.CallToolResult {
+	if len(msgs) == 0 {
+		return toolError(failPrefix, errors.New("拆分后没有可发送的内容"), nil)
+	}
+	if len(msgs) == 1 {
+		return s.sendMessage(ctx, webhookKey, msgs[0], failPrefix, successMsg)
+	}
+
+	// 全部校验通过后再发送，避免只发出一部分
+	for _, msg := range msgs {
+		if err := msg.Validate(); err != nil {
+			return toolError(failPrefix, err, nil)
+		}
+	}
+
+	var retried []string
+	for i, msg := range msgs {
+		if i > 0 && s.splitInterval > 0 {
+			timer := time.NewTimer(s.splitInterval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return toolError(fmt.Sprintf("%s（已发送 %d/%d 条）", failPrefix, i, len(msgs)), ctx.Err(), nil)
+			case <-timer.C:
+			}
+		}
+
+		stats := &wecom.RequestStats{}
+		if err := s.send(ctx, webhookKey, msg, stats); err != nil {
+			return toolError(fmt.Sprintf("%s（已发送 %d/%d 条）", failPrefix, i, len(msgs)), err, stats)
+		}
+		if stats.Attempts > 1 {
+			retried = append(retried, fmt.Sprintf("第 %d 条共尝试 %d 次", i+1, stats.Attempts))
+		}
+	}
+
+	msg := fmt.Sprintf("%s，内容超长已拆分为 %d 条消息发送", successMsg, len(msgs))
+	if len(retried) > 0 {
+		msg += "（" + strings.Join(retried, "，") + "）"
+	}
+	return mcp.NewToolResultText(msg)
+}
+
+// send 经过限流后发送一条已校验的消息，stats 记录实际请求次数
+func (s *Server) send(ctx context.Context, webhookKey string, msg wecom.Message, stats *wecom.RequestStats) error {
+	// 所有工具共享同一个限流器，保证每个机器人不超过企业微信的发送频率
+	if err := s.limiter.Wait(ctx, webhookKey); err != nil {
+		return err
+	}
+
+	// 动态创建wecom客户端，并记录实际请求次数
+	ctx = wecom.ContextWithStats(ctx, stats)
+	return s.newClient(webhookKey).Send(ctx, msg)
+}
+
+// describeMedia 描述素材的媒体ID和过期时间
+func describeMedia(media *wecom.Media) string {
+	desc := fmt.Sprintf("媒体ID: %s，有效期至: %s", media.ID, media.ExpiresAt().Format(time.DateTime))
+	if media.Cached {
+		desc += "（相同文件已上传过，复用缓存的媒体ID）"
+	}
+	return desc
+}
+
+// toolResult 生成成功结果，发生重试时附带尝试次数
+func toolResult(msg string, stats *wecom.RequestStats) *mcp.CallToolResult {
+	return mcp.NewToolResultText(msg + attemptsSuffix(stats))
+}
+
+// attemptsSuffix 发生重试时返回尝试次数说明
+func attemptsSuffix(stats *wecom.RequestStats) string {
+	if stats == nil || stats.Attempts <= 1 {
+		return ""
+	}
+	return fmt.Sprintf("（共尝试 %d 次）", stats.Attempts)
+}
+
+// toolError 将企业微信错误转换为工具错误结果，并根据错误类型附带处理建议
+func toolError(prefix string, err error, stats *wecom.RequestStats) *mcp.CallToolResult {
+	msg := prefix + attemptsSuffix(stats) + ": " + err.Error()
+
+	switch {
+	case wecom.IsInvalidKey(err):
+		msg += "（webhook_key 无效或机器人已被移除，请检查后重试，重试无法解决该问题）"
+	case wecom.IsRateLimited(err):
+		msg += "（触发企业微信频率限制，请稍后重试）"
+	case wecom.IsContentTooLong(err):
+		msg += "（消息内容超出长度限制，请精简或拆分后重试）"
 	}
 
-	return mcp.NewToolResultText("文件上传成功，媒体ID: " + mediaID), nil
+	return mcp.NewToolResultError(msg)
 }
@@ -0,0 +1,331 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"wecom-bot-server-go/internal/wecom"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// testServer 连接本地模拟企业微信接口的服务器
+type testServer struct {
+	*Server
+
+	mu       sync.Mutex
+	payloads []map[string]interface{}
+	// keys 每条消息请求使用的 webhook key
+	keys []string
+}
+
+func newTestServer(t *testing.T, opts ...Option) *testServer {
+	t.Helper()
+	ts := &testServer{}
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/upload_media" {
+			fmt.Fprintf(w, `{"errcode":0,"errmsg":"ok","type":"file","media_id":"test-media-id","created_at":"%d"}`, time.Now().Unix())
+			return
+		}
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		ts.mu.Lock()
+		ts.payloads = append(ts.payloads, payload)
+		ts.keys = append(ts.keys, r.URL.Query().Get("key"))
+		ts.mu.Unlock()
+		io.WriteString(w, `{"errcode":0,"errmsg":"ok"}`)
+	}))
+	t.Cleanup(mock.Close)
+
+	opts = append([]Option{WithClientOptions(wecom.WithBaseURL(mock.URL))}, opts...)
+	ts.Server = New(server.NewMCPServer("test", "0.0.0"), opts...)
+	if err := ts.RegisterTools(context.Background()); err != nil {
+		t.Fatalf("注册工具失败: %v", err)
+	}
+	return ts
+}
+
+// call 通过 MCP 协议调用工具，返回结果文本和是否为错误结果
+func (ts *testServer) call(t *testing.T, name string, args map[string]any) (string, bool) {
+	t.Helper()
+	return ts.callContext(context.Background(), t, name, args)
+}
+
+// callContext 使用指定上下文调用工具
+func (ts *testServer) callContext(ctx context.Context, t *testing.T, name string, args map[string]any) (string, bool) {
+	t.Helper()
+	raw, err := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "tools/call",
+		"params":  map[string]any{"name": name, "arguments": args},
+	})
+	if err != nil {
+		t.Fatalf("序列化请求失败: %v", err)
+	}
+
+	resp, ok := ts.mcpServer.HandleMessage(ctx, raw).(mcp.JSONRPCResponse)
+	if !ok {
+		t.Fatalf("调用工具 %s 失败", name)
+	}
+	result, ok := resp.Result.(mcp.CallToolResult)
+	if !ok || len(result.Content) == 0 {
+		t.Fatalf("工具 %s 返回了无法识别的结果: %#v", name, resp.Result)
+	}
+	text, _ := result.Content[0].(mcp.TextContent)
+	return text.Text, result.IsError
+}
+
+// sent 返回模拟接口收到的消息
+func (ts *testServer) sent() []map[string]interface{} {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return append([]map[string]interface{}(nil), ts.payloads...)
+}
+
+func TestSendTextNoticeCardTool(t *testing.T) {
+	ts := newTestServer(t)
+	text, isErr := ts.call(t, "send-text-notice-card", map[string]any{
+		"webhook_key": "key",
+		"main_title":  map[string]any{"title": "服务告警"},
+		"horizontal_content_list": []any{
+			map[string]any{"keyname": "服务", "value": "api"},
+		},
+		"card_action": map[string]any{"type": float64(1), "url": "https://example.com"},
+	})
+	if isErr {
+		t.Fatalf("发送失败: %s", text)
+	}
+
+	sent := ts.sent()
+	if len(sent) != 1 {
+		t.Fatalf("期望发送 1 条消息，实际 %d 条", len(sent))
+	}
+	card, _ := sent[0]["template_card"].(map[string]interface{})
+	if card["card_type"] != wecom.CardTypeTextNotice {
+		t.Errorf("template_card = %v", card)
+	}
+
+	text, isErr = ts.call(t, "send-text-notice-card", map[string]any{
+		"webhook_key": "key",
+		"card_action": map[string]any{"type": float64(1), "url": "https://example.com"},
+	})
+	if !isErr {
+		t.Errorf("缺少标题时应返回错误，实际: %s", text)
+	}
+}
+
+func TestSendNewsNoticeCardTool(t *testing.T) {
+	ts := newTestServer(t)
+	text, isErr := ts.call(t, "send-news-notice-card", map[string]any{
+		"webhook_key": "key",
+		"main_title":  map[string]any{"title": "v2.0 发布"},
+		"card_image":  map[string]any{"url": "https://example.com/cover.png", "aspect_ratio": 1.5},
+		"vertical_content_list": []any{
+			map[string]any{"title": "新功能", "desc": "支持语音消息"},
+		},
+		"card_action": map[string]any{"type": float64(1), "url": "https://example.com"},
+	})
+	if isErr {
+		t.Fatalf("发送失败: %s", text)
+	}
+
+	card, _ := ts.sent()[0]["template_card"].(map[string]interface{})
+	image, _ := card["card_image"].(map[string]interface{})
+	if card["card_type"] != wecom.CardTypeNewsNotice || image["aspect_ratio"] != 1.5 {
+		t.Errorf("template_card = %v", card)
+	}
+}
+
+func TestSendNewsTool(t *testing.T) {
+	ts := newTestServer(t)
+
+	// 兼容单篇文章参数
+	if text, isErr := ts.call(t, "send-news", map[string]any{
+		"webhook_key": "key",
+		"title":       "单篇文章",
+		"url":         "https://example.com",
+	}); isErr {
+		t.Fatalf("发送失败: %s", text)
+	}
+
+	articles := []any{
+		map[string]any{"title": "第一篇", "url": "https://example.com/1"},
+		map[string]any{"title": "第二篇", "url": "https://example.com/2", "picurl": "https://example.com/2.png"},
+	}
+	if text, isErr := ts.call(t, "send-news", map[string]any{"webhook_key": "key", "articles": articles}); isErr {
+		t.Fatalf("发送失败: %s", text)
+	}
+	news, _ := ts.sent()[1]["news"].(map[string]interface{})
+	if sent, _ := news["articles"].([]interface{}); len(sent) != 2 {
+		t.Errorf("news = %v", news)
+	}
+
+	articles = append(articles, map[string]any{"title": "缺少链接"})
+	text, isErr := ts.call(t, "send-news", map[string]any{"webhook_key": "key", "articles": articles})
+	if !isErr || !strings.Contains(text, "articles[2].url") {
+		t.Errorf("期望提示第 3 篇文章缺少链接，实际: %s", text)
+	}
+}
+
+func TestSendTextSplit(t *testing.T) {
+	ts := newTestServer(t, WithSplitInterval(0))
+	content := strings.Repeat(strings.Repeat("告警", 100)+"\n\n", 8)
+
+	text, isErr := ts.call(t, "send-text", map[string]any{
+		"webhook_key":    "key",
+		"content":        content,
+		"mentioned_list": "@all",
+	})
+	if !isErr {
+		t.Fatalf("未开启拆分时超长内容应返回错误，实际: %s", text)
+	}
+
+	text, isErr = ts.call(t, "send-text", map[string]any{
+		"webhook_key":    "key",
+		"content":        content,
+		"mentioned_list": "@all",
+		"split":          true,
+	})
+	if isErr {
+		t.Fatalf("发送失败: %s", text)
+	}
+
+	sent := ts.sent()
+	if len(sent) < 2 {
+		t.Fatalf("期望拆分为多条消息，实际 %d 条", len(sent))
+	}
+	for i, payload := range sent {
+		msg, _ := payload["text"].(map[string]interface{})
+		part, _ := msg["content"].(string)
+		if len(part) > wecom.MaxTextBytes {
+			t.Errorf("第 %d 条消息长度 %d 超过限制", i+1, len(part))
+		}
+		_, mentioned := msg["mentioned_list"]
+		if last := i == len(sent)-1; mentioned != last {
+			t.Errorf("第 %d 条消息 mentioned_list = %v", i+1, msg["mentioned_list"])
+		}
+	}
+	if !strings.Contains(text, "拆分为") {
+		t.Errorf("结果未说明拆分条数: %s", text)
+	}
+}
+
+func TestSendTextSplitReportsRetries(t *testing.T) {
+	var calls atomic.Int32
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 第二条消息第一次请求时企业微信系统繁忙
+		if calls.Add(1) == 2 {
+			io.WriteString(w, `{"errcode":-1,"errmsg":"system busy"}`)
+			return
+		}
+		io.WriteString(w, `{"errcode":0,"errmsg":"ok"}`)
+	}))
+	t.Cleanup(mock.Close)
+
+	ts := newTestServer(t, WithSplitInterval(0), WithClientOptions(
+		wecom.WithBaseURL(mock.URL),
+		wecom.WithRetryPolicy(wecom.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}),
+	))
+	content := strings.Repeat(strings.Repeat("告警", 100)+"\n\n", 8)
+	text, isErr := ts.call(t, "send-text", map[string]any{"webhook_key": "key", "content": content, "split": true})
+	if isErr {
+		t.Fatalf("发送失败: %s", text)
+	}
+	if !strings.Contains(text, "第 2 条共尝试 2 次") || strings.Contains(text, "第 1 条") {
+		t.Errorf("结果未说明重试次数: %s", text)
+	}
+}
+
+func TestSendSplitBlankContent(t *testing.T) {
+	ts := newTestServer(t, WithSplitInterval(0))
+
+	text, isErr := ts.call(t, "send-text", map[string]any{"webhook_key": "key", "content": strings.Repeat(" \n", 2000), "split": true})
+	if !isErr || !strings.Contains(text, "不能为空") {
+		t.Errorf("send-text 空白内容 = %s", text)
+	}
+	text, isErr = ts.call(t, "send-markdown", map[string]any{"webhook_key": "key", "content": strings.Repeat("\n", 5000), "split": true})
+	if !isErr || !strings.Contains(text, "不能为空") {
+		t.Errorf("send-markdown 空白内容 = %s", text)
+	}
+	if sent := ts.sent(); len(sent) != 0 {
+		t.Errorf("空白内容不应发送消息，实际 %d 条", len(sent))
+	}
+}
+
+func TestSendPartsEmpty(t *testing.T) {
+	ts := newTestServer(t)
+	result := ts.sendParts(context.Background(), "key", nil, "发送文本消息失败", "文本消息发送成功")
+	if !result.IsError {
+		t.Errorf("没有消息时应返回错误: %+v", result)
+	}
+}
+
+func TestSendImageFromFile(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatalf("生成 PNG 失败: %v", err)
+	}
+	root := t.TempDir()
+	ts := newTestServer(t, WithFileAccess(FileAccess{AllowedRoots: []string{root}}))
+	filePath := filepath.Join(root, "chart.png")
+	if err := os.WriteFile(filePath, buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("写入图片失败: %v", err)
+	}
+
+	text, isErr := ts.call(t, "send-image", map[string]any{
+		"webhook_key": "key",
+		"file_path":   filePath,
+	})
+	if isErr {
+		t.Fatalf("发送失败: %s", text)
+	}
+	if !strings.Contains(text, "PNG 4x4") {
+		t.Errorf("结果未包含图片尺寸: %s", text)
+	}
+	sent := ts.sent()
+	if len(sent) != 1 || sent[0]["msgtype"] != "image" {
+		t.Fatalf("sent = %v", sent)
+	}
+
+	text, isErr = ts.call(t, "send-image", map[string]any{"webhook_key": "key"})
+	if !isErr {
+		t.Errorf("未提供图片时应返回错误，实际: %s", text)
+	}
+}
+
+func TestUploadFileFromBase64(t *testing.T) {
+	ts := newTestServer(t)
+	text, isErr := ts.call(t, "upload-file", map[string]any{
+		"webhook_key":    "key",
+		"content_base64": base64.StdEncoding.EncodeToString([]byte("daily report")),
+		"filename":       "report.txt",
+	})
+	if isErr || !strings.Contains(text, "test-media-id") {
+		t.Fatalf("上传失败: %s", text)
+	}
+
+	text, isErr = ts.call(t, "upload-file", map[string]any{
+		"webhook_key":    "key",
+		"content_base64": "!!!",
+	})
+	if !isErr {
+		t.Errorf("非法 Base64 应返回错误，实际: %s", text)
+	}
+}
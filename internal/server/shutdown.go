@@ -0,0 +1,164 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"wecom-bot-server-go/internal/auth"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// DefaultShutdownTimeout 关闭服务器时等待进行中的工具调用完成的默认时间
+const DefaultShutdownTimeout = 30 * time.Second
+
+// inflightCall 进行中的工具调用，关闭超时时记录到日志
+type inflightCall struct {
+	tool    string
+	caller  string
+	bot     string
+	started time.Time
+}
+
+// calls 跟踪进行中的工具调用，关闭时拒绝新的调用并等待已有调用完成
+type calls struct {
+	mu      sync.Mutex
+	wg      sync.WaitGroup
+	closing bool
+	nextID  uint64
+	active  map[uint64]inflightCall
+
+	// force 等待超时后取消，中止仍未完成的调用
+	force       context.Context
+	forceCancel context.CancelFunc
+}
+
+// newCalls 创建调用跟踪器
+func newCalls() *calls {
+	force, cancel := context.WithCancel(context.Background())
+	return &calls{
+		active:      make(map[uint64]inflightCall),
+		force:       force,
+		forceCancel: cancel,
+	}
+}
+
+// begin 登记一次调用，服务器正在关闭时返回 false
+func (c *calls) begin(call inflightCall) (uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closing {
+		return 0, false
+	}
+	c.nextID++
+	c.active[c.nextID] = call
+	c.wg.Add(1)
+	return c.nextID, true
+}
+
+// end 结束一次调用
+func (c *calls) end(id uint64) {
+	c.mu.Lock()
+	delete(c.active, id)
+	c.mu.Unlock()
+	c.wg.Done()
+}
+
+// isClosing 判断服务器是否正在关闭
+func (c *calls) isClosing() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closing
+}
+
+// detach 返回调用使用的 context：服务器关闭导致请求被取消时调用继续执行，
+// 直到完成或等待超时；未关闭时请求被取消（例如客户端断开）仍会中止调用。保留原截止时间。
+func (c *calls) detach(ctx context.Context) (context.Context, context.CancelFunc) {
+	detached, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancelDeadline context.CancelFunc
+		detached, cancelDeadline = context.WithDeadline(detached, deadline)
+		parentCancel := cancel
+		cancel = func() {
+			cancelDeadline()
+			parentCancel()
+		}
+	}
+	stopRequest := context.AfterFunc(ctx, func() {
+		if !c.isClosing() {
+			cancel()
+		}
+	})
+	stopForce := context.AfterFunc(c.force, cancel)
+	return detached, func() {
+		stopRequest()
+		stopForce()
+		cancel()
+	}
+}
+
+// tracked 跟踪工具调用，服务器关闭后拒绝新的调用
+func (s *Server) tracked(tool string, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		call := inflightCall{tool: tool, caller: AnonymousCaller, bot: s.policyBot(request.GetArguments()), started: time.Now()}
+		if id, ok := auth.FromContext(ctx); ok {
+			call.caller = id.Name
+		}
+		id, ok := s.calls.begin(call)
+		if !ok {
+			return mcp.NewToolResultError("服务器正在关闭，不再接受新的调用，请稍后重试"), nil
+		}
+		defer s.calls.end(id)
+
+		ctx, cancel := s.calls.detach(ctx)
+		defer cancel()
+		return handler(ctx, request)
+	}
+}
+
+// BeginShutdown 停止接受新的工具调用，进行中的调用不受影响
+func (s *Server) BeginShutdown() {
+	s.calls.mu.Lock()
+	s.calls.closing = true
+	s.calls.mu.Unlock()
+}
+
+// Shutdown 停止接受新的工具调用，并等待进行中的调用（包括排队等待限流和拆分发送的消息）完成。
+// ctx 结束时仍未完成的调用会被中止并记录到日志。
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.BeginShutdown()
+
+	done := make(chan struct{})
+	go func() {
+		s.calls.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	s.calls.mu.Lock()
+	pending := make([]inflightCall, 0, len(s.calls.active))
+	for _, call := range s.calls.active {
+		pending = append(pending, call)
+	}
+	s.calls.mu.Unlock()
+
+	for _, call := range pending {
+		slog.Error("关闭超时，中止未完成的工具调用",
+			"tool", call.tool,
+			"caller", call.caller,
+			"bot", call.bot,
+			"elapsed", time.Since(call.started).Round(time.Millisecond).String(),
+		)
+	}
+	s.calls.forceCancel()
+	return fmt.Errorf("等待 %d 个进行中的工具调用完成超时: %w", len(pending), ctx.Err())
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"wecom-bot-server-go/internal/wecom"
+)
+
+// callResult 在协程中调用工具的结果
+type callResult struct {
+	text  string
+	isErr bool
+}
+
+// newBlockingServer 创建收到消息后阻塞到调用 release 的测试服务器
+func newBlockingServer(t *testing.T) (*testServer, chan struct{}, func()) {
+	t.Helper()
+	started := make(chan struct{}, 1)
+	unblock := make(chan struct{})
+	var once sync.Once
+	release := func() { once.Do(func() { close(unblock) }) }
+
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		select {
+		case <-unblock:
+			io.WriteString(w, `{"errcode":0,"errmsg":"ok"}`)
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(mock.Close)
+	t.Cleanup(release)
+
+	ts := newTestServer(t, WithClientOptions(
+		wecom.WithBaseURL(mock.URL),
+		wecom.WithRetryPolicy(wecom.RetryPolicy{MaxAttempts: 1}),
+	))
+	return ts, started, release
+}
+
+// callAsync 在协程中调用工具
+func (ts *testServer) callAsync(ctx context.Context, t *testing.T, name string, args map[string]any) <-chan callResult {
+	done := make(chan callResult, 1)
+	go func() {
+		text, isErr := ts.callContext(ctx, t, name, args)
+		done <- callResult{text, isErr}
+	}()
+	return done
+}
+
+func TestShutdownDrainsInflightCalls(t *testing.T) {
+	ts, started, release := newBlockingServer(t)
+
+	// 请求的 context 在关闭时被取消（例如传输层关闭），进行中的发送不应中止
+	reqCtx, cancelReq := context.WithCancel(context.Background())
+	done := ts.callAsync(reqCtx, t, "send-text", map[string]any{"webhook_key": "key", "content": "部署通知"})
+	<-started
+
+	shutdownErr := make(chan error, 1)
+	go func() { shutdownErr <- ts.Shutdown(context.Background()) }()
+	for !ts.calls.isClosing() {
+		time.Sleep(time.Millisecond)
+	}
+	cancelReq()
+
+	text, isErr := ts.call(t, "send-text", map[string]any{"webhook_key": "key", "content": "新消息"})
+	if !isErr || !strings.Contains(text, "正在关闭") {
+		t.Errorf("关闭后的新调用 = %s", text)
+	}
+
+	select {
+	case err := <-shutdownErr:
+		t.Fatalf("进行中的调用完成前 Shutdown 返回了 %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release()
+	if result := <-done; result.isErr {
+		t.Errorf("进行中的调用失败: %s", result.text)
+	}
+	if err := <-shutdownErr; err != nil {
+		t.Errorf("Shutdown() error = %v", err)
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	ts, started, _ := newBlockingServer(t)
+
+	done := ts.callAsync(context.Background(), t, "send-text", map[string]any{"webhook_key": "key", "content": "部署通知"})
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := ts.Shutdown(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "1 个") {
+		t.Errorf("Shutdown() error = %v", err)
+	}
+
+	select {
+	case result := <-done:
+		if !result.isErr {
+			t.Errorf("超时后被中止的调用应返回错误: %s", result.text)
+		}
+	case <-time.After(time.Second):
+		t.Error("超时后进行中的调用没有被中止")
+	}
+}
+
+func TestRequestCancelBeforeShutdown(t *testing.T) {
+	ts, started, _ := newBlockingServer(t)
+
+	// 未关闭时客户端断开仍会中止调用
+	reqCtx, cancelReq := context.WithCancel(context.Background())
+	done := ts.callAsync(reqCtx, t, "send-text", map[string]any{"webhook_key": "key", "content": "hi"})
+	<-started
+	cancelReq()
+
+	select {
+	case result := <-done:
+		if !result.isErr {
+			t.Errorf("请求取消后调用应返回错误: %s", result.text)
+		}
+	case <-time.After(time.Second):
+		t.Error("请求取消后调用没有中止")
+	}
+}
@@ -0,0 +1,225 @@
+package server
+
+import (
+	"context"
+
+	"wecom-bot-server-go/internal/wecom"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// stringProp 字符串字段的 JSON Schema
+func stringProp(description string) map[string]any {
+	return map[string]any{"type": "string", "description": description}
+}
+
+// intProp 整数字段的 JSON Schema
+func intProp(description string, values ...int) map[string]any {
+	schema := map[string]any{"type": "integer", "description": description}
+	if len(values) > 0 {
+		schema["enum"] = values
+	}
+	return schema
+}
+
+// objectSchema 对象的 JSON Schema
+func objectSchema(props map[string]any, required ...string) map[string]any {
+	schema := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// 模板卡片各字段的 JSON Schema，与企业微信接口字段一一对应
+var (
+	cardSourceProps = map[string]any{
+		"icon_url":   stringProp("来源图片的URL"),
+		"desc":       stringProp("来源图片的描述，建议不超过13个字"),
+		"desc_color": intProp("来源文字的颜色：0 灰色（默认），1 黑色，2 红色，3 绿色", 0, 1, 2, 3),
+	}
+	cardMainTitleProps = map[string]any{
+		"title": stringProp("一级标题，建议不超过26个字"),
+		"desc":  stringProp("标题辅助信息，建议不超过30个字"),
+	}
+	cardEmphasisContentProps = map[string]any{
+		"title": stringProp("关键数据样式的数据内容，建议不超过10个字"),
+		"desc":  stringProp("关键数据样式的数据描述内容，建议不超过15个字"),
+	}
+	cardQuoteAreaProps = map[string]any{
+		"type":       intProp("点击事件类型：0 没有点击事件，1 跳转URL，2 跳转小程序", 0, 1, 2),
+		"url":        stringProp("点击跳转的URL，type 为 1 时必填"),
+		"appid":      stringProp("点击跳转的小程序appid，type 为 2 时必填"),
+		"pagepath":   stringProp("点击跳转的小程序pagepath"),
+		"title":      stringProp("引用文献样式的标题"),
+		"quote_text": stringProp("引用文献样式的引用文案"),
+	}
+	cardHorizontalContentSchema = objectSchema(map[string]any{
+		"type":     intProp("链接类型：0 普通文本，1 跳转URL，2 下载附件，3 点击跳转成员详情", 0, 1, 2, 3),
+		"keyname":  stringProp("二级标题，建议不超过5个字"),
+		"value":    stringProp("二级文本，建议不超过26个字"),
+		"url":      stringProp("链接跳转的URL，type 为 1 时必填"),
+		"media_id": stringProp("附件的媒体ID，type 为 2 时必填"),
+		"userid":   stringProp("被查看详情的成员userid，type 为 3 时必填"),
+	}, "keyname")
+	cardJumpSchema = objectSchema(map[string]any{
+		"type":     intProp("跳转类型：0 不跳转，1 跳转URL，2 跳转小程序", 0, 1, 2),
+		"title":    stringProp("跳转链接样式的文案内容，建议不超过13个字"),
+		"url":      stringProp("跳转的URL，type 为 1 时必填"),
+		"appid":    stringProp("跳转的小程序appid，type 为 2 时必填"),
+		"pagepath": stringProp("跳转的小程序pagepath"),
+	}, "title")
+	cardImageProps = map[string]any{
+		"url": stringProp("图片的URL"),
+		"aspect_ratio": map[string]any{
+			"type":        "number",
+			"description": "图片的宽高比，取值范围 1.3~2.25，默认 1.3",
+			"minimum":     wecom.MinCardImageAspectRatio,
+			"maximum":     wecom.MaxCardImageAspectRatio,
+		},
+	}
+	cardImageTextAreaProps = map[string]any{
+		"type":      intProp("左图右文样式区域点击事件：0 没有点击事件，1 跳转URL，2 跳转小程序", 0, 1, 2),
+		"url":       stringProp("点击跳转的URL，type 为 1 时必填"),
+		"appid":     stringProp("点击跳转的小程序appid，type 为 2 时必填"),
+		"pagepath":  stringProp("点击跳转的小程序pagepath"),
+		"title":     stringProp("左图右文样式的标题"),
+		"desc":      stringProp("左图右文样式的描述"),
+		"image_url": stringProp("左图右文样式的图片URL"),
+	}
+	cardVerticalContentSchema = objectSchema(map[string]any{
+		"title": stringProp("二级标题，建议不超过26个字"),
+		"desc":  stringProp("二级文本，建议不超过112个字"),
+	}, "title")
+	cardActionProps = map[string]any{
+		"type":     intProp("卡片跳转类型：1 跳转URL，2 打开小程序", 1, 2),
+		"url":      stringProp("跳转的URL，type 为 1 时必填"),
+		"appid":    stringProp("跳转的小程序appid，type 为 2 时必填"),
+		"pagepath": stringProp("跳转的小程序pagepath"),
+	}
+)
+
+// registerSendTextNoticeCardTool 注册发送文本通知模板卡片工具
+func (s *Server) registerSendTextNoticeCardTool() error {
+	tool := mcp.NewTool("send-text-notice-card",
+		mcp.WithDescription("发送文本通知模板卡片到企业微信群，main_title.title 与 sub_title_text 至少填写一项"),
+		mcp.WithObject("source",
+			mcp.Description("卡片来源样式信息"),
+			mcp.Properties(cardSourceProps),
+		),
+		mcp.WithObject("main_title",
+			mcp.Description("模板卡片的主要内容，包括一级标题和标题辅助信息"),
+			mcp.Properties(cardMainTitleProps),
+		),
+		mcp.WithObject("emphasis_content",
+			mcp.Description("关键数据样式"),
+			mcp.Properties(cardEmphasisContentProps),
+		),
+		mcp.WithObject("quote_area",
+			mcp.Description("引用文献样式，建议不与关键数据样式共用"),
+			mcp.Properties(cardQuoteAreaProps),
+		),
+		mcp.WithString("sub_title_text",
+			mcp.Description("二级普通文本，建议不超过112个字"),
+		),
+		mcp.WithArray("horizontal_content_list",
+			mcp.Description("二级标题+文本列表，最多6条"),
+			mcp.Items(cardHorizontalContentSchema),
+			mcp.MaxItems(wecom.MaxHorizontalContents),
+		),
+		mcp.WithArray("jump_list",
+			mcp.Description("跳转指引样式的列表，最多3条"),
+			mcp.Items(cardJumpSchema),
+			mcp.MaxItems(wecom.MaxJumps),
+		),
+		mcp.WithObject("card_action",
+			mcp.Required(),
+			mcp.Description("整体卡片的点击跳转事件"),
+			mcp.Properties(cardActionProps),
+		),
+	)
+
+	s.addTool(tool, s.handleSendTextNoticeCard)
+	return nil
+}
+
+// handleSendTextNoticeCard 处理发送文本通知模板卡片
+func (s *Server) handleSendTextNoticeCard(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	webhookKey, err := s.webhookKey(request.GetArguments())
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	var card wecom.TextNoticeCard
+	if err := request.BindArguments(&card); err != nil {
+		return mcp.NewToolResultError("解析参数失败: " + err.Error()), nil
+	}
+
+	return s.sendMessage(ctx, webhookKey, &card, "发送文本通知模板卡片失败", "文本通知模板卡片发送成功"), nil
+}
+
+// registerSendNewsNoticeCardTool 注册发送图文展示模板卡片工具
+func (s *Server) registerSendNewsNoticeCardTool() error {
+	tool := mcp.NewTool("send-news-notice-card",
+		mcp.WithDescription("发送图文展示模板卡片到企业微信群，支持封面图片、左图右文、垂直内容和链接列表"),
+		mcp.WithObject("source",
+			mcp.Description("卡片来源样式信息"),
+			mcp.Properties(cardSourceProps),
+		),
+		mcp.WithObject("main_title",
+			mcp.Required(),
+			mcp.Description("模板卡片的主要内容，title 必填"),
+			mcp.Properties(cardMainTitleProps),
+		),
+		mcp.WithObject("card_image",
+			mcp.Description("图片样式，与 image_text_area 至少提供其中之一"),
+			mcp.Properties(cardImageProps),
+		),
+		mcp.WithObject("image_text_area",
+			mcp.Description("左图右文样式，与 card_image 至少提供其中之一"),
+			mcp.Properties(cardImageTextAreaProps),
+		),
+		mcp.WithObject("quote_area",
+			mcp.Description("引用文献样式"),
+			mcp.Properties(cardQuoteAreaProps),
+		),
+		mcp.WithArray("vertical_content_list",
+			mcp.Description("卡片二级垂直内容，最多4条"),
+			mcp.Items(cardVerticalContentSchema),
+			mcp.MaxItems(wecom.MaxVerticalContents),
+		),
+		mcp.WithArray("horizontal_content_list",
+			mcp.Description("二级标题+文本列表，最多6条"),
+			mcp.Items(cardHorizontalContentSchema),
+			mcp.MaxItems(wecom.MaxHorizontalContents),
+		),
+		mcp.WithArray("jump_list",
+			mcp.Description("跳转指引样式的列表，最多3条"),
+			mcp.Items(cardJumpSchema),
+			mcp.MaxItems(wecom.MaxJumps),
+		),
+		mcp.WithObject("card_action",
+			mcp.Required(),
+			mcp.Description("整体卡片的点击跳转事件"),
+			mcp.Properties(cardActionProps),
+		),
+	)
+
+	s.addTool(tool, s.handleSendNewsNoticeCard)
+	return nil
+}
+
+// handleSendNewsNoticeCard 处理发送图文展示模板卡片
+func (s *Server) handleSendNewsNoticeCard(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	webhookKey, err := s.webhookKey(request.GetArguments())
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	var card wecom.NewsNoticeCard
+	if err := request.BindArguments(&card); err != nil {
+		return mcp.NewToolResultError("解析参数失败: " + err.Error()), nil
+	}
+
+	return s.sendMessage(ctx, webhookKey, &card, "发送图文展示模板卡片失败", "图文展示模板卡片发送成功"), nil
+}
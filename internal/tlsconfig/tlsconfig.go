@@ -0,0 +1,173 @@
+// Package tlsconfig 根据证书文件创建 HTTPS 服务使用的 TLS 配置，证书文件更新后自动重新加载
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// CertCheckInterval 检查证书文件是否更新的最小间隔
+const CertCheckInterval = 10 * time.Second
+
+// Options TLS 配置选项
+type Options struct {
+	// CertFile、KeyFile PEM 格式的服务器证书和私钥
+	CertFile string
+	KeyFile  string
+	// ClientCAFile 校验客户端证书使用的 CA，为空时不要求客户端证书
+	ClientCAFile string
+	// RequireClientCert 为 true 时拒绝未提供有效客户端证书的连接，否则只校验提供了的证书
+	RequireClientCert bool
+	// MinVersion 最低 TLS 版本："1.2" 或 "1.3"，为空时为 1.2
+	MinVersion string
+}
+
+// ParseVersion 解析 TLS 版本号
+func ParseVersion(version string) (uint16, error) {
+	switch version {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("不支持的 TLS 版本 %q，可选 1.2、1.3", version)
+	}
+}
+
+// New 创建 TLS 配置，服务器证书在握手时按需重新加载
+func New(opts Options) (*tls.Config, error) {
+	if opts.CertFile == "" || opts.KeyFile == "" {
+		return nil, errors.New("需要同时指定证书文件和私钥文件")
+	}
+	minVersion, err := ParseVersion(opts.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+	reloader, err := NewCertReloader(opts.CertFile, opts.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		MinVersion:     minVersion,
+		GetCertificate: reloader.GetCertificate,
+	}
+	if opts.ClientCAFile != "" {
+		pool, err := loadCertPool(opts.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+		if opts.RequireClientCert {
+			config.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	} else if opts.RequireClientCert {
+		return nil, errors.New("要求客户端证书时必须指定客户端 CA 文件")
+	}
+	return config, nil
+}
+
+// loadCertPool 从 PEM 文件加载 CA 证书
+func loadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取客户端 CA 文件失败: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("客户端 CA 文件 %s 中没有有效的 PEM 证书", path)
+	}
+	return pool, nil
+}
+
+// CertReloader 在证书或私钥文件的修改时间变化后重新加载证书，
+// 证书续期后无需重启服务器。重新加载失败时继续使用原证书。
+type CertReloader struct {
+	certFile string
+	keyFile  string
+	interval time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+	checked time.Time
+}
+
+// NewCertReloader 加载证书并返回重新加载器
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	r := &CertReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		interval: CertCheckInterval,
+		now:      time.Now,
+	}
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.load(modTime); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate 实现 tls.Config.GetCertificate，距上次检查超过间隔时检查文件是否更新
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	if now.Sub(r.checked) < r.interval {
+		return r.cert, nil
+	}
+	r.checked = now
+
+	modTime, err := r.latestModTime()
+	if err != nil {
+		slog.Warn("检查 TLS 证书文件失败，继续使用原证书", "error", err.Error())
+		return r.cert, nil
+	}
+	if modTime.Equal(r.modTime) {
+		return r.cert, nil
+	}
+	if err := r.load(modTime); err != nil {
+		slog.Warn("重新加载 TLS 证书失败，继续使用原证书", "error", err.Error())
+		return r.cert, nil
+	}
+	slog.Info("已重新加载 TLS 证书", "cert", r.certFile)
+	return r.cert, nil
+}
+
+// load 加载证书，调用方需持有锁或在初始化时调用
+func (r *CertReloader) load(modTime time.Time) error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("加载 TLS 证书失败: %w", err)
+	}
+	r.cert = &cert
+	r.modTime = modTime
+	return nil
+}
+
+// latestModTime 返回证书和私钥文件中较晚的修改时间
+func (r *CertReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, path := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("读取 TLS 证书文件失败: %w", err)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
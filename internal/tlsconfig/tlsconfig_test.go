@@ -0,0 +1,141 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCert 生成自签名证书并写入 dir，返回证书和私钥文件路径
+func writeCert(t *testing.T, dir, cn string) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+// commonName 返回证书的 Common Name
+func commonName(t *testing.T, cert *tls.Certificate) string {
+	t.Helper()
+	parsed, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed.Subject.CommonName
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCert(t, dir, "old.example.com")
+
+	r, err := NewCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("NewCertReloader() error = %v", err)
+	}
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	cert, _ := r.GetCertificate(nil)
+	if cn := commonName(t, cert); cn != "old.example.com" {
+		t.Fatalf("CN = %q", cn)
+	}
+
+	// 证书续期：写入新证书并推后修改时间
+	writeCert(t, dir, "new.example.com")
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, later, later)
+	os.Chtimes(keyFile, later, later)
+
+	// 检查间隔内继续使用原证书
+	cert, _ = r.GetCertificate(nil)
+	if cn := commonName(t, cert); cn != "old.example.com" {
+		t.Errorf("检查间隔内 CN = %q", cn)
+	}
+
+	now = now.Add(CertCheckInterval)
+	cert, _ = r.GetCertificate(nil)
+	if cn := commonName(t, cert); cn != "new.example.com" {
+		t.Errorf("重新加载后 CN = %q", cn)
+	}
+
+	// 文件损坏时继续使用原证书
+	os.WriteFile(certFile, []byte("invalid"), 0o600)
+	evenLater := later.Add(time.Minute)
+	os.Chtimes(certFile, evenLater, evenLater)
+	now = now.Add(CertCheckInterval)
+	cert, err = r.GetCertificate(nil)
+	if err != nil || commonName(t, cert) != "new.example.com" {
+		t.Errorf("证书损坏后 CN = %q, err = %v", commonName(t, cert), err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCert(t, dir, "server.example.com")
+
+	config, err := New(Options{CertFile: certFile, KeyFile: keyFile, MinVersion: "1.3"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if config.MinVersion != tls.VersionTLS13 || config.ClientAuth != tls.NoClientCert {
+		t.Errorf("config = %+v", config)
+	}
+
+	// 自签名证书同时作为客户端 CA
+	config, err = New(Options{CertFile: certFile, KeyFile: keyFile, ClientCAFile: certFile, RequireClientCert: true})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if config.ClientCAs == nil || config.ClientAuth != tls.RequireAndVerifyClientCert || config.MinVersion != tls.VersionTLS12 {
+		t.Errorf("config = %+v", config)
+	}
+
+	invalid := []Options{
+		{CertFile: certFile},
+		{CertFile: certFile, KeyFile: keyFile, MinVersion: "1.0"},
+		{CertFile: certFile, KeyFile: keyFile, RequireClientCert: true},
+		{CertFile: certFile, KeyFile: keyFile, ClientCAFile: keyFile},
+	}
+	for _, opts := range invalid {
+		if _, err := New(opts); err == nil {
+			t.Errorf("New(%+v) 应返回错误", opts)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package wecom
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+)
+
+// 语音素材限制
+const (
+	// MaxVoiceBytes 语音文件最大字节数
+	MaxVoiceBytes = 2 << 20
+	// MaxVoiceDuration 语音最长播放时间
+	MaxVoiceDuration = 60 * time.Second
+)
+
+// amrMagic AMR-NB 文件头
+var amrMagic = []byte("#!AMR\n")
+
+// amrFrameSizes AMR-NB 各帧类型的语音数据字节数（不含 1 字节帧头），每帧 20ms
+var amrFrameSizes = [16]int{12, 13, 15, 17, 19, 20, 26, 31, 5, 0, 0, 0, 0, 0, 0, 0}
+
+// amrFrameDuration AMR 每帧时长
+const amrFrameDuration = 20 * time.Millisecond
+
+// AMRDuration 解析 AMR-NB 文件并返回语音时长
+func AMRDuration(data []byte) (time.Duration, error) {
+	if !bytes.HasPrefix(data, amrMagic) {
+		return 0, fmt.Errorf("不是 AMR 格式的语音文件")
+	}
+
+	frames := 0
+	for pos := len(amrMagic); pos < len(data); frames++ {
+		frameType := (data[pos] >> 3) & 0x0F
+		pos += 1 + amrFrameSizes[frameType]
+		if pos > len(data) {
+			return 0, fmt.Errorf("AMR 文件在第 %d 帧处被截断", frames+1)
+		}
+	}
+	return time.Duration(frames) * amrFrameDuration, nil
+}
+
+// validateVoice 校验语音素材的格式、大小和时长
+func validateVoice(data []byte) error {
+	if len(data) > MaxVoiceBytes {
+		return fmt.Errorf("语音文件大小为 %d 字节，超过 %d 字节限制", len(data), MaxVoiceBytes)
+	}
+	duration, err := AMRDuration(data)
+	if err != nil {
+		return err
+	}
+	if duration > MaxVoiceDuration {
+		return fmt.Errorf("语音时长为 %s，超过 %s 限制", duration, MaxVoiceDuration)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package wecom
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+// makeAMR 生成包含 frames 个 12.2kbps 帧的 AMR-NB 数据
+func makeAMR(frames int) []byte {
+	frame := append([]byte{0x3C}, make([]byte, 31)...)
+	return append([]byte("#!AMR\n"), bytes.Repeat(frame, frames)...)
+}
+
+func TestAMRDuration(t *testing.T) {
+	d, err := AMRDuration(makeAMR(150))
+	if err != nil {
+		t.Fatalf("AMRDuration failed: %v", err)
+	}
+	if d != 3*time.Second {
+		t.Errorf("AMRDuration = %v, want 3s", d)
+	}
+
+	if _, err := AMRDuration([]byte("ID3 not amr")); err == nil {
+		t.Errorf("非 AMR 文件应返回错误")
+	}
+	truncated := makeAMR(2)
+	if _, err := AMRDuration(truncated[:len(truncated)-3]); err == nil {
+		t.Errorf("被截断的 AMR 文件应返回错误")
+	}
+}
+
+func TestValidateVoice(t *testing.T) {
+	if err := validateVoice(makeAMR(3000)); err != nil {
+		t.Errorf("60 秒语音应校验通过: %v", err)
+	}
+	if err := validateVoice(makeAMR(3001)); err == nil {
+		t.Errorf("超过 60 秒的语音应校验失败")
+	}
+}
+
+func TestUploadVoice(t *testing.T) {
+	mock := newMockWeCom(t)
+	client := mock.client()
+
+	if _, err := client.UploadBytes(context.Background(), "voice.mp3", []byte("not amr"), MediaTypeVoice); err == nil {
+		t.Fatalf("非 AMR 语音应在上传前被拒绝")
+	}
+	if len(mock.requests) != 0 {
+		t.Fatalf("校验失败时不应发出请求")
+	}
+
+	if _, err := client.UploadBytes(context.Background(), "voice.amr", makeAMR(50), MediaTypeVoice); err != nil {
+		t.Fatalf("UploadBytes failed: %v", err)
+	}
+	if req := mock.lastRequest(t); req.Query["type"][0] != "voice" {
+		t.Errorf("type = %v, want voice", req.Query["type"])
+	}
+	if err := client.SendVoice("test-media-id"); err != nil {
+		t.Fatalf("SendVoice failed: %v", err)
+	}
+	if payload := mock.lastPayload(t); payload["msgtype"] != "voice" {
+		t.Errorf("msgtype = %v", payload["msgtype"])
+	}
+}
@@ -0,0 +1,190 @@
+package wecom
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MediaCacheMargin 缓存的素材距过期不足该时间时不再复用，避免发送时恰好过期
+const MediaCacheMargin = time.Hour
+
+// CachedMedia 缓存中的一条素材记录
+type CachedMedia struct {
+	MediaID   string    `json:"media_id"`
+	MediaType MediaType `json:"media_type"`
+	Filename  string    `json:"filename"`
+	// SHA256 文件内容的 SHA-256 哈希
+	SHA256    string    `json:"sha256"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// MediaCache 按 webhook key 和文件内容哈希缓存已上传素材的媒体ID，
+// 相同内容在有效期内重复上传时直接复用。可选持久化到 JSON 文件，重启后继续生效。
+type MediaCache struct {
+	mu sync.Mutex
+	// path 持久化文件路径，为空时只保存在内存中
+	path    string
+	entries map[string]map[string]CachedMedia
+	now     func() time.Time
+}
+
+// NewMediaCache 创建素材缓存，path 非空时从该文件加载已有记录并在更新后写回
+func NewMediaCache(path string) (*MediaCache, error) {
+	c := &MediaCache{
+		path:    path,
+		entries: make(map[string]map[string]CachedMedia),
+		now:     time.Now,
+	}
+	if path == "" {
+		return c, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取素材缓存失败: %w", err)
+	}
+	if err := json.Unmarshal(data, &c.entries); err != nil {
+		return nil, fmt.Errorf("解析素材缓存失败: %w", err)
+	}
+	c.purge()
+	return c, nil
+}
+
+// cacheKey 同一机器人下素材的缓存键，文件名不同时群聊中显示不同，不能复用
+func cacheKey(mediaType MediaType, filename, sum string) string {
+	return string(mediaType) + "/" + filename + "/" + sum
+}
+
+// valid 判断记录是否仍可复用
+func (c *MediaCache) valid(entry CachedMedia) bool {
+	return c.now().Add(MediaCacheMargin).Before(entry.ExpiresAt)
+}
+
+// Get 查找未过期的素材
+func (c *MediaCache) Get(webhookKey string, mediaType MediaType, filename, sum string) (CachedMedia, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[webhookKey][cacheKey(mediaType, filename, sum)]
+	if !ok || !c.valid(entry) {
+		return CachedMedia{}, false
+	}
+	return entry, true
+}
+
+// Put 记录上传成功的素材
+func (c *MediaCache) Put(webhookKey string, entry CachedMedia) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries[webhookKey] == nil {
+		c.entries[webhookKey] = make(map[string]CachedMedia)
+	}
+	c.entries[webhookKey][cacheKey(entry.MediaType, entry.Filename, entry.SHA256)] = entry
+	c.purge()
+	return c.save()
+}
+
+// List 返回机器人所有未过期的素材，按上传时间从新到旧排列
+func (c *MediaCache) List(webhookKey string) []CachedMedia {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	list := make([]CachedMedia, 0, len(c.entries[webhookKey]))
+	for _, entry := range c.entries[webhookKey] {
+		if c.valid(entry) {
+			list = append(list, entry)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.After(list[j].CreatedAt)
+	})
+	return list
+}
+
+// purge 清理过期记录，调用方需持有锁
+func (c *MediaCache) purge() {
+	for key, entries := range c.entries {
+		for k, entry := range entries {
+			if !c.valid(entry) {
+				delete(entries, k)
+			}
+		}
+		if len(entries) == 0 {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// save 将缓存写入持久化文件，先写临时文件再重命名，避免写入中断时损坏，调用方需持有锁
+func (c *MediaCache) save() error {
+	if c.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化素材缓存失败: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("写入素材缓存失败: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("写入素材缓存失败: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("写入素材缓存失败: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return fmt.Errorf("写入素材缓存失败: %w", err)
+	}
+	return nil
+}
+
+// WithMediaCache 上传素材前先按内容哈希查找缓存，有效期内的相同文件直接复用媒体ID
+func WithMediaCache(cache *MediaCache) Option {
+	return func(c *Client) {
+		c.mediaCache = cache
+	}
+}
+
+// hashSource 计算可重新定位的 reader 剩余内容的 SHA-256 并回到原位置，
+// 无法重新定位时返回 false
+func hashSource(r io.Reader) (string, int64, bool, error) {
+	seeker, ok := r.(io.ReadSeeker)
+	if !ok {
+		return "", 0, false, nil
+	}
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", 0, false, nil
+	}
+
+	h := sha256.New()
+	n, err := io.Copy(h, seeker)
+	if err != nil {
+		return "", 0, false, fmt.Errorf("读取文件失败: %w", err)
+	}
+	if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+		return "", 0, false, fmt.Errorf("读取文件失败: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, true, nil
+}
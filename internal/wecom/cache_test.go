@@ -0,0 +1,94 @@
+package wecom
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMediaCacheReusesUpload(t *testing.T) {
+	cache, _ := NewMediaCache("")
+	now := time.Unix(1380000000, 0).Add(time.Hour)
+	cache.now = func() time.Time { return now }
+
+	mock := newMockWeCom(t)
+	client := mock.client(WithMediaCache(cache))
+	upload := func(client *Client, filename, content string) *Media {
+		t.Helper()
+		media, err := client.UploadBytes(context.Background(), filename, []byte(content), MediaTypeFile)
+		if err != nil {
+			t.Fatalf("UploadBytes failed: %v", err)
+		}
+		return media
+	}
+	uploads := func() int {
+		mock.mu.Lock()
+		defer mock.mu.Unlock()
+		return len(mock.requests)
+	}
+
+	if media := upload(client, "report.txt", "daily report"); media.Cached {
+		t.Error("首次上传不应命中缓存")
+	}
+	media := upload(client, "report.txt", "daily report")
+	if !media.Cached || media.ID != "test-media-id" || uploads() != 1 {
+		t.Errorf("相同内容应复用缓存: cached=%v uploads=%d", media.Cached, uploads())
+	}
+
+	// 文件名、内容或机器人不同时重新上传
+	upload(client, "weekly.txt", "daily report")
+	upload(client, "report.txt", "weekly report")
+	upload(NewClient("other-key", WithBaseURL(mock.URL), WithMediaCache(cache)), "report.txt", "daily report")
+	if uploads() != 4 {
+		t.Errorf("期望上传 4 次，实际 %d 次", uploads())
+	}
+	if list := cache.List(testWebhookKey); len(list) != 3 {
+		t.Errorf("List = %+v", list)
+	}
+
+	// 临近过期的素材不再复用
+	now = time.Unix(1380000000, 0).Add(MediaExpiry - MediaCacheMargin/2)
+	if media := upload(client, "report.txt", "daily report"); media.Cached {
+		t.Error("临近过期的素材不应复用")
+	}
+}
+
+func TestMediaCachePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "media-cache.json")
+	cache, err := NewMediaCache(path)
+	if err != nil {
+		t.Fatalf("NewMediaCache failed: %v", err)
+	}
+
+	created := time.Now().Truncate(time.Second)
+	entry := CachedMedia{
+		MediaID:   "media-1",
+		MediaType: MediaTypeFile,
+		Filename:  "report.txt",
+		SHA256:    "abc",
+		Size:      12,
+		CreatedAt: created,
+		ExpiresAt: created.Add(MediaExpiry),
+	}
+	if err := cache.Put("key", entry); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	expired := entry
+	expired.MediaID, expired.SHA256, expired.ExpiresAt = "media-2", "def", created.Add(-time.Minute)
+	if err := cache.Put("key", expired); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	loaded, err := NewMediaCache(path)
+	if err != nil {
+		t.Fatalf("重新加载缓存失败: %v", err)
+	}
+	got, ok := loaded.Get("key", MediaTypeFile, "report.txt", "abc")
+	if !ok || got.MediaID != "media-1" || !got.ExpiresAt.Equal(entry.ExpiresAt) {
+		t.Errorf("Get = %+v, %v", got, ok)
+	}
+	if list := loaded.List("key"); len(list) != 1 {
+		t.Errorf("过期记录应被清理: %+v", list)
+	}
+}
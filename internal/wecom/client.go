@@ -2,12 +2,18 @@ package wecom
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"mime/multipart"
 	"net/http"
-	"os"
+	"net/http/httptrace"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
 )
 
 const (
@@ -22,74 +28,216 @@ const (
 // Client 企业微信机器人客户端
 type Client struct {
 	webhookKey string
+	baseURL    string
+	userAgent  string
 	httpClient *http.Client
+	transport  http.RoundTripper
+	timeout    time.Duration
+
+	retryPolicy RetryPolicy
+	mediaCache  *MediaCache
+	// imageAddrAllowed 判断下载网络图片时能否连接该地址
+	imageAddrAllowed func(netip.Addr) bool
 }
 
 // NewClient 创建新的企业微信机器人客户端
-func NewClient(webhookKey string) *Client {
-	return &Client{
+func NewClient(webhookKey string, opts ...Option) *Client {
+	c := &Client{
 		webhookKey: webhookKey,
+		baseURL:    WeComBotBaseURL,
+		userAgent:  DefaultUserAgent,
 		httpClient: &http.Client{},
+
+		retryPolicy:      DefaultRetryPolicy,
+		imageAddrAllowed: IsPublicAddr,
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
+
+	// 复制一份 HTTP 客户端再覆盖传输层和超时，避免修改调用方共享的实例
+	if c.transport != nil || c.timeout > 0 {
+		httpClient := *c.httpClient
+		if c.transport != nil {
+			httpClient.Transport = c.transport
+		}
+		if c.timeout > 0 {
+			httpClient.Timeout = c.timeout
+		}
+		c.httpClient = &httpClient
+	}
+
+	return c
 }
 
-// SendText 发送文本消息
-func (c *Client) SendText(content string, mentionedList, mentionedMobileList []string) error {
-	payload := map[string]interface{}{
-		"msgtype": "text",
-		"text": map[string]interface{}{
-			"content":               content,
-			"mentioned_list":        mentionedList,
-			"mentioned_mobile_list": mentionedMobileList,
-		},
+// endpoint 生成带 webhook key 的接口地址
+func (c *Client) endpoint(path string, query url.Values) string {
+	if query == nil {
+		query = url.Values{}
 	}
+	query.Set("key", c.webhookKey)
+	return c.baseURL + "/" + path + "?" + query.Encode()
+}
 
-	return c.sendRequest(WeComBotSendURL, payload)
+// requestBody 请求体，每次尝试都会调用 open 重新生成，以便重试时重新发送
+type requestBody struct {
+	contentType string
+	// length 请求体字节数，未知时为 -1，此时使用分块传输编码
+	length int64
+	open   func() (io.ReadCloser, error)
+	// resendable 为 true 时请求已发出但未收到响应也可以重试，例如重复上传素材没有副作用
+	resendable bool
 }
 
-// SendMarkdown 发送Markdown消息
-func (c *Client) SendMarkdown(content string) error {
-	payload := map[string]interface{}{
-		"msgtype": "markdown",
-		"markdown": map[string]interface{}{
-			"content": content,
+// errBodyNotReplayable 请求体无法重新读取，不能再重试
+var errBodyNotReplayable = errors.New("请求体无法重新读取")
+
+// bytesBody 内存中的请求体
+func bytesBody(contentType string, data []byte) requestBody {
+	return requestBody{
+		contentType: contentType,
+		length:      int64(len(data)),
+		open: func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(data)), nil
 		},
 	}
+}
+
+// do 发送 POST 请求并解析响应，临时错误按重试策略重试，ctx 取消时请求随之中止
+func (c *Client) do(ctx context.Context, rawURL string, body requestBody) (*apiResponse, error) {
+	stats := statsFromContext(ctx)
+	maxAttempts := max(c.retryPolicy.MaxAttempts, 1)
+
+	var lastErr error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		// 先生成请求体，读取源文件失败或请求体无法重新读取时不再等待重试
+		reader, err := body.open()
+		if err != nil {
+			if lastErr != nil && errors.Is(err, errBodyNotReplayable) {
+				return nil, lastErr
+			}
+			return nil, err
+		}
+
+		if attempt > 1 {
+			wait := c.retryPolicy.backoff(attempt-1, lastErr)
+			// 剩余时间不足以等待时直接返回上一次的错误
+			if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+				reader.Close()
+				return nil, lastErr
+			}
+			if err := sleepContext(ctx, wait); err != nil {
+				reader.Close()
+				return nil, lastErr
+			}
+		}
 
-	return c.sendRequest(WeComBotSendURL, payload)
+		if stats != nil {
+			stats.Attempts++
+		}
+		result, err := c.post(ctx, rawURL, body.contentType, body.length, reader)
+		if err == nil {
+			return result, nil
+		}
+		lastErr = err
+		if !IsRetryable(err) || ctx.Err() != nil {
+			break
+		}
+		if errors.Is(err, ErrDeliveryUnknown) && !body.resendable {
+			break
+		}
+	}
+	return nil, lastErr
 }
 
-// SendImage 发送图片消息
-func (c *Client) SendImage(base64Data, md5 string) error {
-	payload := map[string]interface{}{
-		"msgtype": "image",
-		"image": map[string]interface{}{
-			"base64": base64Data,
-			"md5":    md5,
+// post 发送单次 POST 请求并附带通用请求头，length 为 -1 时使用分块传输编码
+func (c *Client) post(ctx context.Context, rawURL, contentType string, length int64, body io.ReadCloser) (*apiResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rawURL, body)
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	req.ContentLength = length
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", c.userAgent)
+
+	// 记录请求是否已完整写出，之后的失败无法确定企业微信是否已处理
+	var wrote atomic.Bool
+	req = req.WithContext(httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		WroteRequest: func(info httptrace.WroteRequestInfo) {
+			wrote.Store(info.Err == nil)
 		},
+	}))
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if wrote.Load() {
+			return nil, fmt.Errorf("发送请求失败: %w: %w", ErrDeliveryUnknown, err)
+		}
+		return nil, fmt.Errorf("发送请求失败: %w", err)
 	}
+	defer resp.Body.Close()
+
+	return decodeResponse(resp)
+}
+
+// Send 校验并发送一条消息
+func (c *Client) Send(ctx context.Context, m Message) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+
+	payload, err := MarshalMessage(m)
+	if err != nil {
+		return fmt.Errorf("序列化请求数据失败: %w", err)
+	}
+
+	_, err = c.do(ctx, c.endpoint("send", nil), bytesBody("application/json", payload))
+	return err
+}
+
+// SendText 发送文本消息
+func (c *Client) SendText(content string, mentionedList, mentionedMobileList []string) error {
+	return c.SendTextContext(context.Background(), content, mentionedList, mentionedMobileList)
+}
+
+// SendTextContext 使用指定上下文发送文本消息
+func (c *Client) SendTextContext(ctx context.Context, content string, mentionedList, mentionedMobileList []string) error {
+	return c.Send(ctx, &TextMessage{
+		Content:             content,
+		MentionedList:       mentionedList,
+		MentionedMobileList: mentionedMobileList,
+	})
+}
+
+// SendMarkdown 发送Markdown消息
+func (c *Client) SendMarkdown(content string) error {
+	return c.SendMarkdownContext(context.Background(), content)
+}
 
-	return c.sendRequest(WeComBotSendURL, payload)
+// SendMarkdownContext 使用指定上下文发送Markdown消息
+func (c *Client) SendMarkdownContext(ctx context.Context, content string) error {
+	return c.Send(ctx, &MarkdownMessage{Content: content})
 }
 
-// NewsArticle 新闻文章结构
-type NewsArticle struct {
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	URL         string `json:"url"`
-	PicURL      string `json:"picurl"`
+// SendImage 发送图片消息
+func (c *Client) SendImage(base64Data, md5 string) error {
+	return c.SendImageContext(context.Background(), base64Data, md5)
+}
+
+// SendImageContext 使用指定上下文发送图片消息
+func (c *Client) SendImageContext(ctx context.Context, base64Data, md5 string) error {
+	return c.Send(ctx, &ImageMessage{Base64: base64Data, MD5: md5})
 }
 
 // SendNews 发送图文消息
 func (c *Client) SendNews(articles []NewsArticle) error {
-	payload := map[string]interface{}{
-		"msgtype": "news",
-		"news": map[string]interface{}{
-			"articles": articles,
-		},
-	}
+	return c.SendNewsContext(context.Background(), articles)
+}
 
-	return c.sendRequest(WeComBotSendURL, payload)
+// SendNewsContext 使用指定上下文发送图文消息
+func (c *Client) SendNewsContext(ctx context.Context, articles []NewsArticle) error {
+	return c.Send(ctx, &NewsMessage{Articles: articles})
 }
 
 // TemplateCardParams 模板卡片参数
@@ -103,102 +251,83 @@ type TemplateCardParams struct {
 	CardActionPagePath string
 }
 
-// SendTemplateCard 发送模板卡片消息
-func (c *Client) SendTemplateCard(params TemplateCardParams) error {
-	payload := map[string]interface{}{
-		"msgtype": "template_card",
-		"template_card": map[string]interface{}{
-			"card_type": params.CardType,
-			"main_title": map[string]interface{}{
-				"title": params.MainTitle,
-				"desc":  params.MainDesc,
-			},
-			"card_action": map[string]interface{}{
-				"type":     params.CardActionType,
-				"url":      params.CardActionURL,
-				"appid":    params.CardActionAppID,
-				"pagepath": params.CardActionPagePath,
-			},
+// Message 将参数转换为模板卡片消息
+func (p TemplateCardParams) Message() *TemplateCardMessage {
+	return &TemplateCardMessage{
+		CardType: p.CardType,
+		MainTitle: &CardMainTitle{
+			Title: p.MainTitle,
+			Desc:  p.MainDesc,
+		},
+		CardAction: &CardAction{
+			Type:     p.CardActionType,
+			URL:      p.CardActionURL,
+			AppID:    p.CardActionAppID,
+			PagePath: p.CardActionPagePath,
 		},
 	}
+}
 
-	return c.sendRequest(WeComBotSendURL, payload)
+// SendTemplateCard 发送模板卡片消息
+func (c *Client) SendTemplateCard(params TemplateCardParams) error {
+	return c.SendTemplateCardContext(context.Background(), params)
+}
+
+// SendTemplateCardContext 使用指定上下文发送模板卡片消息
+func (c *Client) SendTemplateCardContext(ctx context.Context, params TemplateCardParams) error {
+	return c.Send(ctx, params.Message())
 }
 
 // UploadFile 上传文件并返回媒体ID
 func (c *Client) UploadFile(filePath string) (string, error) {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return "", fmt.Errorf("打开文件失败: %w", err)
-	}
-	defer file.Close()
-
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-
-	part, err := writer.CreateFormFile("media", filePath)
-	if err != nil {
-		return "", fmt.Errorf("创建表单文件失败: %w", err)
-	}
-
-	_, err = io.Copy(part, file)
-	if err != nil {
-		return "", fmt.Errorf("复制文件内容失败: %w", err)
-	}
-
-	err = writer.Close()
-	if err != nil {
-		return "", fmt.Errorf("关闭写入器失败: %w", err)
-	}
+	return c.UploadFileContext(context.Background(), filePath)
+}
 
-	url := fmt.Sprintf("%s%s&type=file", WeComBotUploadURL, c.webhookKey)
-	resp, err := c.httpClient.Post(url, writer.FormDataContentType(), body)
+// UploadFileContext 使用指定上下文上传文件并返回媒体ID
+func (c *Client) UploadFileContext(ctx context.Context, filePath string) (string, error) {
+	media, err := c.UploadMedia(ctx, filePath, MediaTypeFile)
 	if err != nil {
-		return "", fmt.Errorf("上传文件请求失败: %w", err)
-	}
-	defer resp.Body.Close()
-
-	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", fmt.Errorf("解析响应失败: %w", err)
-	}
-
-	if errCode, ok := result["errcode"].(float64); ok && errCode != 0 {
-		errMsg, _ := result["errmsg"].(string)
-		return "", fmt.Errorf("企业微信API错误: %s", errMsg)
-	}
-
-	mediaID, ok := result["media_id"].(string)
-	if !ok {
-		return "", fmt.Errorf("无法获取媒体ID")
+		return "", err
 	}
+	return media.ID, nil
+}
 
-	return mediaID, nil
+// apiResponse 企业微信接口的通用响应
+type apiResponse struct {
+	ErrCode   int    `json:"errcode"`
+	ErrMsg    string `json:"errmsg"`
+	Type      string `json:"type"`
+	MediaID   string `json:"media_id"`
+	CreatedAt string `json:"created_at"`
 }
 
-// sendRequest 发送请求到企业微信API
-func (c *Client) sendRequest(urlTemplate string, payload map[string]interface{}) error {
-	jsonPayload, err := json.Marshal(payload)
+// decodeResponse 解析响应，HTTP 错误或非零 errcode 均返回 *APIError
+func decodeResponse(resp *http.Response) (*apiResponse, error) {
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
 	if err != nil {
-		return fmt.Errorf("序列化请求数据失败: %w", err)
+		return nil, fmt.Errorf("读取响应失败: %w: %w", ErrDeliveryUnknown, err)
 	}
 
-	url := urlTemplate + c.webhookKey
-	resp, err := c.httpClient.Post(url, "application/json", bytes.NewBuffer(jsonPayload))
-	if err != nil {
-		return fmt.Errorf("发送请求失败: %w", err)
+	fail := func(errCode int, errMsg string) (*apiResponse, error) {
+		apiErr := newAPIError(resp.StatusCode, errCode, errMsg)
+		apiErr.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+		return nil, apiErr
 	}
-	defer resp.Body.Close()
 
-	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return fmt.Errorf("解析响应失败: %w", err)
+	var result apiResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return fail(0, strings.ToValidUTF8(strings.TrimSpace(string(body[:min(len(body), 256)])), ""))
+		}
+		return nil, fmt.Errorf("解析响应失败: %w", err)
 	}
 
-	if errCode, ok := result["errcode"].(float64); ok && errCode != 0 {
-		errMsg, _ := result["errmsg"].(string)
-		return fmt.Errorf("企业微信API错误: %s", errMsg)
+	if result.ErrCode != 0 {
+		return fail(result.ErrCode, result.ErrMsg)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fail(0, http.StatusText(resp.StatusCode))
 	}
 
-	return nil
+	return &result, nil
 }
@@ -2,35 +2,156 @@
 package wecom
 
 import (
+	"context"
 	"crypto/md5"
 	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 )
 
 const (
 	testWebhookKey = "测试的WebhookKey" // 替换为实际的测试WebhookKey
 )
 
+// recordedRequest 模拟服务收到的请求
+type recordedRequest struct {
+	Path        string
+	Query       map[string][]string
+	Header      http.Header
+	Body        []byte
+	ContentType string
+}
+
+// mockWeCom 本地模拟的企业微信机器人接口
+type mockWeCom struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []recordedRequest
+	handler  func(w http.ResponseWriter, r *http.Request, body []byte)
+}
+
+func newMockWeCom(t *testing.T) *mockWeCom {
+	t.Helper()
+	m := &mockWeCom{}
+	m.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		m.mu.Lock()
+		m.requests = append(m.requests, recordedRequest{
+			Path:        r.URL.Path,
+			Query:       r.URL.Query(),
+			Header:      r.Header.Clone(),
+			Body:        body,
+			ContentType: r.Header.Get("Content-Type"),
+		})
+		handler := m.handler
+		m.mu.Unlock()
+
+		if handler != nil {
+			handler(w, r, body)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/upload_media" {
+			io.WriteString(w, `{"errcode":0,"errmsg":"ok","type":"file","media_id":"test-media-id","created_at":"1380000000"}`)
+			return
+		}
+		io.WriteString(w, `{"errcode":0,"errmsg":"ok"}`)
+	}))
+	t.Cleanup(m.Close)
+	return m
+}
+
+func (m *mockWeCom) client(opts ...Option) *Client {
+	return NewClient(testWebhookKey, append([]Option{WithBaseURL(m.URL)}, opts...)...)
+}
+
+func (m *mockWeCom) lastRequest(t *testing.T) recordedRequest {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.requests) == 0 {
+		t.Fatalf("模拟服务未收到请求")
+	}
+	return m.requests[len(m.requests)-1]
+}
+
+func (m *mockWeCom) lastPayload(t *testing.T) map[string]interface{} {
+	t.Helper()
+	var payload map[string]interface{}
+	if err := json.Unmarshal(m.lastRequest(t).Body, &payload); err != nil {
+		t.Fatalf("解析请求体失败: %v", err)
+	}
+	return payload
+}
+
+func TestNewClientOptions(t *testing.T) {
+	shared := &http.Client{}
+	client := NewClient(testWebhookKey,
+		WithBaseURL("http://example.com/webhook/"),
+		WithHTTPClient(shared),
+		WithTimeout(3*time.Second),
+		WithUserAgent("test-agent"),
+	)
+	if client.baseURL != "http://example.com/webhook" {
+		t.Errorf("baseURL = %q", client.baseURL)
+	}
+	if client.userAgent != "test-agent" {
+		t.Errorf("userAgent = %q", client.userAgent)
+	}
+	if client.httpClient == shared {
+		t.Errorf("设置超时时不应修改共享的 HTTP 客户端")
+	}
+	if client.httpClient.Timeout != 3*time.Second || shared.Timeout != 0 {
+		t.Errorf("超时设置不正确: %v / %v", client.httpClient.Timeout, shared.Timeout)
+	}
+}
+
 func TestSendText(t *testing.T) {
-	client := NewClient(testWebhookKey)
+	mock := newMockWeCom(t)
+	client := mock.client(WithUserAgent("test-agent"))
 	err := client.SendText("测试文本消息", []string{"user1", "user2"}, []string{"13800000000", "13900000000"})
 	if err != nil {
 		t.Fatalf("SendText failed: %v", err)
 	}
+
+	req := mock.lastRequest(t)
+	if req.Path != "/send" || req.Query["key"][0] != testWebhookKey {
+		t.Errorf("请求地址不正确: %s %v", req.Path, req.Query)
+	}
+	if req.Header.Get("User-Agent") != "test-agent" {
+		t.Errorf("User-Agent = %q", req.Header.Get("User-Agent"))
+	}
+	payload := mock.lastPayload(t)
+	if payload["msgtype"] != "text" {
+		t.Errorf("msgtype = %v", payload["msgtype"])
+	}
 }
 
 func TestSendMarkdown(t *testing.T) {
-	client := NewClient(testWebhookKey)
+	mock := newMockWeCom(t)
+	client := mock.client()
 	err := client.SendMarkdown("### 测试Markdown消息\n> 这是一条测试")
 	if err != nil {
 		t.Fatalf("SendMarkdown failed: %v", err)
 	}
+	if payload := mock.lastPayload(t); payload["msgtype"] != "markdown" {
+		t.Errorf("msgtype = %v", payload["msgtype"])
+	}
 }
 
 func TestSendImage(t *testing.T) {
-	client := NewClient(testWebhookKey)
+	mock := newMockWeCom(t)
+	client := mock.client()
 	filePath := "test.jpg"
 	data, err := os.ReadFile(filePath)
 	if os.IsNotExist(err) {
@@ -46,6 +167,10 @@ func TestSendImage(t *testing.T) {
 	if err != nil {
 		t.Fatalf("SendImage failed: %v", err)
 	}
+	image, _ := mock.lastPayload(t)["image"].(map[string]interface{})
+	if image["md5"] != md5Str {
+		t.Errorf("md5 = %v, want %s", image["md5"], md5Str)
+	}
 }
 
 func encodeToBase64(data []byte) string {
@@ -58,7 +183,8 @@ func calcMD5(data []byte) string {
 }
 
 func TestSendNews(t *testing.T) {
-	client := NewClient(testWebhookKey)
+	mock := newMockWeCom(t)
+	client := mock.client()
 	articles := []NewsArticle{
 		{
 			Title:       "测试图文标题",
@@ -71,10 +197,14 @@ func TestSendNews(t *testing.T) {
 	if err != nil {
 		t.Fatalf("SendNews failed: %v", err)
 	}
+	if payload := mock.lastPayload(t); payload["msgtype"] != "news" {
+		t.Errorf("msgtype = %v", payload["msgtype"])
+	}
 }
 
 func TestSendTemplateCard(t *testing.T) {
-	client := NewClient(testWebhookKey)
+	mock := newMockWeCom(t)
+	client := mock.client()
 	params := TemplateCardParams{
 		CardType:           "text_notice",
 		MainTitle:          "模板卡片主标题",
@@ -88,20 +218,59 @@ func TestSendTemplateCard(t *testing.T) {
 	if err != nil {
 		t.Fatalf("SendTemplateCard failed: %v", err)
 	}
+	if payload := mock.lastPayload(t); payload["msgtype"] != "template_card" {
+		t.Errorf("msgtype = %v", payload["msgtype"])
+	}
 }
 
 func TestUploadFile(t *testing.T) {
-	client := NewClient(testWebhookKey)
-	// 请确保 test.txt 文件存在于项目根目录
-	_, err := os.Stat("test.txt")
-	if os.IsNotExist(err) {
-		t.Skip("test.txt 文件不存在，跳过上传文件测试")
+	mock := newMockWeCom(t)
+	client := mock.client()
+	filePath := filepath.Join(t.TempDir(), "test.txt")
+	if err := os.WriteFile(filePath, []byte("hello wecom"), 0o644); err != nil {
+		t.Fatalf("写入测试文件失败: %v", err)
 	}
-	mediaID, err := client.UploadFile("test.txt")
+	mediaID, err := client.UploadFile(filePath)
 	if err != nil {
 		t.Fatalf("UploadFile failed: %v", err)
 	}
 	if mediaID == "" {
 		t.Fatalf("UploadFile 返回空 mediaID")
 	}
+
+	req := mock.lastRequest(t)
+	if req.Path != "/upload_media" || req.Query["type"][0] != "file" {
+		t.Errorf("上传地址不正确: %s %v", req.Path, req.Query)
+	}
+}
+
+func TestAPIErrorResponse(t *testing.T) {
+	mock := newMockWeCom(t)
+	mock.handler = func(w http.ResponseWriter, r *http.Request, body []byte) {
+		io.WriteString(w, `{"errcode":93000,"errmsg":"invalid webhook url"}`)
+	}
+	client := mock.client()
+	if err := client.SendMarkdown("test"); err == nil {
+		t.Fatalf("期望返回错误")
+	}
+}
+
+func TestSendContextCanceled(t *testing.T) {
+	mock := newMockWeCom(t)
+	release := make(chan struct{})
+	defer close(release)
+	mock.handler = func(w http.ResponseWriter, r *http.Request, body []byte) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}
+	client := mock.client()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := client.SendMarkdownContext(ctx, "test")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("期望返回 context.DeadlineExceeded，实际: %v", err)
+	}
 }
@@ -0,0 +1,101 @@
+package wecom
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// 企业微信常见错误码
+const (
+	// ErrCodeSystemBusy 系统繁忙
+	ErrCodeSystemBusy = -1
+	// ErrCodeInvalidParameter 参数不合法，内容超长时也会返回该错误码
+	ErrCodeInvalidParameter = 40058
+	// ErrCodeContentSizeOutOfLimit 消息内容大小超过限制
+	ErrCodeContentSizeOutOfLimit = 45002
+	// ErrCodeRateLimited 接口调用频率超过限制
+	ErrCodeRateLimited = 45009
+	// ErrCodeInvalidWebhook webhook key 无效
+	ErrCodeInvalidWebhook = 93000
+)
+
+// hintPattern 匹配 errmsg 中的请求标识，例如 "hint: [1700000000_xxx]"
+var hintPattern = regexp.MustCompile(`hint: \[([^\]]+)\]`)
+
+// APIError 企业微信接口返回的错误
+type APIError struct {
+	// ErrCode 企业微信错误码，HTTP 层错误时为 0
+	ErrCode int
+	// ErrMsg 企业微信错误信息
+	ErrMsg string
+	// StatusCode HTTP 状态码
+	StatusCode int
+	// Hint 企业微信返回的请求标识，便于向官方排查问题
+	Hint string
+	// RetryAfter 响应头 Retry-After 建议的等待时间，未提供时为 0
+	RetryAfter time.Duration
+}
+
+// newAPIError 根据响应内容创建 APIError
+func newAPIError(statusCode, errCode int, errMsg string) *APIError {
+	e := &APIError{
+		ErrCode:    errCode,
+		ErrMsg:     errMsg,
+		StatusCode: statusCode,
+	}
+	if m := hintPattern.FindStringSubmatch(errMsg); m != nil {
+		e.Hint = m[1]
+	}
+	return e
+}
+
+// Error 实现 error 接口
+func (e *APIError) Error() string {
+	if e.ErrCode == 0 {
+		return fmt.Sprintf("企业微信API错误: HTTP %d %s", e.StatusCode, e.ErrMsg)
+	}
+	return fmt.Sprintf("企业微信API错误: %s (errcode=%d)", e.ErrMsg, e.ErrCode)
+}
+
+// asAPIError 从错误链中取出 APIError
+func asAPIError(err error) (*APIError, bool) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr, true
+	}
+	return nil, false
+}
+
+// IsRateLimited 判断错误是否由调用频率超限导致
+func IsRateLimited(err error) bool {
+	apiErr, ok := asAPIError(err)
+	if !ok {
+		return false
+	}
+	return apiErr.ErrCode == ErrCodeRateLimited || apiErr.StatusCode == http.StatusTooManyRequests
+}
+
+// IsInvalidKey 判断错误是否由 webhook key 无效导致
+func IsInvalidKey(err error) bool {
+	apiErr, ok := asAPIError(err)
+	return ok && apiErr.ErrCode == ErrCodeInvalidWebhook
+}
+
+// IsContentTooLong 判断错误是否由消息内容超长导致
+func IsContentTooLong(err error) bool {
+	apiErr, ok := asAPIError(err)
+	if !ok {
+		return false
+	}
+	switch apiErr.ErrCode {
+	case ErrCodeContentSizeOutOfLimit:
+		return true
+	case ErrCodeInvalidParameter:
+		return strings.Contains(apiErr.ErrMsg, "exceed max length")
+	}
+	return false
+}
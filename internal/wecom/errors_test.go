@@ -0,0 +1,68 @@
+package wecom
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestAPIErrorClassification(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         *APIError
+		rateLimited bool
+		invalidKey  bool
+		tooLong     bool
+	}{
+		{"rate limited", newAPIError(200, ErrCodeRateLimited, "api freq out of limit"), true, false, false},
+		{"http 429", newAPIError(http.StatusTooManyRequests, 0, "Too Many Requests"), true, false, false},
+		{"invalid key", newAPIError(200, ErrCodeInvalidWebhook, "invalid webhook url"), false, true, false},
+		{"text too long", newAPIError(200, ErrCodeInvalidParameter, "text.content exceed max length 2048"), false, false, true},
+		{"invalid parameter", newAPIError(200, ErrCodeInvalidParameter, "invalid msgtype"), false, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := fmt.Errorf("wrapped: %w", tt.err)
+			if got := IsRateLimited(err); got != tt.rateLimited {
+				t.Errorf("IsRateLimited = %v", got)
+			}
+			if got := IsInvalidKey(err); got != tt.invalidKey {
+				t.Errorf("IsInvalidKey = %v", got)
+			}
+			if got := IsContentTooLong(err); got != tt.tooLong {
+				t.Errorf("IsContentTooLong = %v", got)
+			}
+		})
+	}
+}
+
+func TestAPIErrorFromResponse(t *testing.T) {
+	mock := newMockWeCom(t)
+	mock.handler = func(w http.ResponseWriter, r *http.Request, body []byte) {
+		io.WriteString(w, `{"errcode":93000,"errmsg":"invalid webhook url, hint: [1700000000_abc], from ip: 1.2.3.4"}`)
+	}
+	err := mock.client().SendMarkdown("test")
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("期望返回 *APIError，实际: %v", err)
+	}
+	if apiErr.ErrCode != ErrCodeInvalidWebhook || apiErr.StatusCode != http.StatusOK || apiErr.Hint != "1700000000_abc" {
+		t.Errorf("APIError 字段不正确: %+v", apiErr)
+	}
+}
+
+func TestAPIErrorFromHTTPStatus(t *testing.T) {
+	mock := newMockWeCom(t)
+	mock.handler = func(w http.ResponseWriter, r *http.Request, body []byte) {
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	}
+	err := mock.client(WithRetryPolicy(NoRetry)).SendMarkdown("test")
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadGateway {
+		t.Fatalf("期望返回 HTTP 502 的 *APIError，实际: %v", err)
+	}
+}
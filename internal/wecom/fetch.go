@@ -0,0 +1,88 @@
+package wecom
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// maxImageRedirects 下载网络图片时允许的最大重定向次数
+const maxImageRedirects = 5
+
+// reservedPrefixes IsPublicAddr 额外拒绝的地址段，net/netip 未将其归为私有地址
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),      // 本网络
+	netip.MustParsePrefix("100.64.0.0/10"),  // 运营商级 NAT，部分云厂商的元数据服务位于此段
+	netip.MustParsePrefix("192.0.0.0/24"),   // IETF 协议分配
+	netip.MustParsePrefix("198.18.0.0/15"),  // 网络性能测试
+	netip.MustParsePrefix("64:ff9b::/96"),   // NAT64，可能映射到内网 IPv4 地址
+	netip.MustParsePrefix("64:ff9b:1::/48"), // 本地 NAT64
+}
+
+// IsPublicAddr 判断地址是否为公网地址，回环、内网、链路本地（包括云服务器元数据服务）、
+// 组播等地址返回 false
+func IsPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkImageURL 只允许下载 http 和 https 地址的图片
+func checkImageURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("图片地址只支持 http 和 https，不支持 %q", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return errors.New("图片地址缺少主机名")
+	}
+	return nil
+}
+
+// imageHTTPClient 下载网络图片使用的 HTTP 客户端。调用方可以传入任意地址，为防止借此访问
+// 服务器所在的内网，每次建立连接时都检查实际连接的 IP（重定向和 DNS 解析结果同样受限），
+// 并且不经过代理直接连接，保证检查的就是目标地址。
+func (c *Client) imageHTTPClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			addr, err := netip.ParseAddr(host)
+			if err != nil {
+				return err
+			}
+			if !c.imageAddrAllowed(addr) {
+				return fmt.Errorf("不允许下载内网或保留地址 %s 上的图片", addr)
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: c.timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+			DisableKeepAlives:   true,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxImageRedirects {
+				return fmt.Errorf("重定向超过 %d 次", maxImageRedirects)
+			}
+			return checkImageURL(req.URL)
+		},
+	}
+}
@@ -0,0 +1,160 @@
+package wecom
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+)
+
+// MaxImageBytes 图片消息中图片（Base64 编码前）的最大字节数
+const MaxImageBytes = 2 << 20
+
+// 图片消息支持的图片格式
+const (
+	ImageFormatJPEG = "jpeg"
+	ImageFormatPNG  = "png"
+)
+
+// DetectImageFormat 根据文件头识别图片格式，无法识别时返回空字符串
+func DetectImageFormat(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("\xff\xd8\xff")):
+		return ImageFormatJPEG
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return ImageFormatPNG
+	}
+	return ""
+}
+
+// validateImage 校验图片的格式和大小
+func validateImage(data []byte) error {
+	if len(data) == 0 {
+		return fmt.Errorf("图片内容为空")
+	}
+	if len(data) > MaxImageBytes {
+		return fmt.Errorf("图片大小为 %d 字节，超过 %d 字节限制", len(data), MaxImageBytes)
+	}
+	if DetectImageFormat(data) == "" {
+		return fmt.Errorf("图片格式不支持，仅支持 JPG 和 PNG")
+	}
+	return nil
+}
+
+// NewImageMessage 根据图片原始内容创建图片消息，自动计算 Base64 编码和 MD5
+func NewImageMessage(data []byte) (*ImageMessage, error) {
+	if err := validateImage(data); err != nil {
+		return nil, err
+	}
+	sum := md5.Sum(data)
+	return &ImageMessage{
+		Base64: base64.StdEncoding.EncodeToString(data),
+		MD5:    hex.EncodeToString(sum[:]),
+	}, nil
+}
+
+// ReadImage 读取待预处理的图片内容，超过 MaxImageSourceBytes 时不再继续读取
+func ReadImage(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxImageSourceBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("读取图片失败: %w", err)
+	}
+	if len(data) > MaxImageSourceBytes {
+		return nil, fmt.Errorf("图片大小超过预处理上限 %d 字节", MaxImageSourceBytes)
+	}
+	return data, nil
+}
+
+// ReadImageFile 读取本地图片文件
+func ReadImageFile(filePath string) ([]byte, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("打开图片失败: %w", err)
+	}
+	defer file.Close()
+
+	// 先检查大小，避免读入过大的文件
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("读取文件信息失败: %w", err)
+	}
+	if info.Size() > MaxImageSourceBytes {
+		return nil, fmt.Errorf("图片大小为 %d 字节，超过预处理上限 %d 字节", info.Size(), MaxImageSourceBytes)
+	}
+	return ReadImage(file)
+}
+
+// FetchImage 下载网络图片，只允许 http 和 https 地址，拒绝连接回环、内网和链路本地等非公网地址
+func (c *Client) FetchImage(ctx context.Context, imageURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imageURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("图片地址无效: %w", err)
+	}
+	if err := checkImageURL(req.URL); err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+
+	resp, err := c.imageHTTPClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("下载图片失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("下载图片失败: HTTP %d", resp.StatusCode)
+	}
+	if resp.ContentLength > MaxImageSourceBytes {
+		return nil, fmt.Errorf("图片大小为 %d 字节，超过预处理上限 %d 字节", resp.ContentLength, MaxImageSourceBytes)
+	}
+	return ReadImage(resp.Body)
+}
+
+// SendImageBytes 发送图片原始内容，必要时先经 PrepareImage 转换格式和压缩大小，
+// 再自动计算 Base64 编码和 MD5
+func (c *Client) SendImageBytes(ctx context.Context, data []byte) (*PreparedImage, error) {
+	prepared, err := PrepareImage(data)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := NewImageMessage(prepared.Data)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Send(ctx, msg); err != nil {
+		return nil, err
+	}
+	return prepared, nil
+}
+
+// SendImageReader 读取并发送图片
+func (c *Client) SendImageReader(ctx context.Context, r io.Reader) (*PreparedImage, error) {
+	data, err := ReadImage(r)
+	if err != nil {
+		return nil, err
+	}
+	return c.SendImageBytes(ctx, data)
+}
+
+// SendImageFile 发送本地图片文件
+func (c *Client) SendImageFile(ctx context.Context, filePath string) (*PreparedImage, error) {
+	data, err := ReadImageFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return c.SendImageBytes(ctx, data)
+}
+
+// SendImageURL 下载网络图片后发送
+func (c *Client) SendImageURL(ctx context.Context, imageURL string) (*PreparedImage, error) {
+	data, err := c.FetchImage(ctx, imageURL)
+	if err != nil {
+		return nil, err
+	}
+	return c.SendImageBytes(ctx, data)
+}
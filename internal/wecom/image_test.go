@@ -0,0 +1,156 @@
+package wecom
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// makePNG 生成指定尺寸的 PNG 图片
+func makePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		img.Set(x, 0, color.RGBA{R: uint8(x), A: 0xff})
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("生成 PNG 失败: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestNewImageMessage(t *testing.T) {
+	data := makePNG(t, 16, 16)
+	msg, err := NewImageMessage(data)
+	if err != nil {
+		t.Fatalf("NewImageMessage failed: %v", err)
+	}
+	if msg.MD5 != calcMD5(data) || msg.Base64 != encodeToBase64(data) {
+		t.Errorf("msg = %+v", msg)
+	}
+	if err := msg.Validate(); err != nil {
+		t.Errorf("Validate failed: %v", err)
+	}
+
+	if _, err := NewImageMessage([]byte("GIF89a")); err == nil || !strings.Contains(err.Error(), "JPG") {
+		t.Errorf("GIF 图片应返回格式错误，实际: %v", err)
+	}
+	large := append([]byte("\xff\xd8\xff"), make([]byte, MaxImageBytes)...)
+	if _, err := NewImageMessage(large); err == nil || !strings.Contains(err.Error(), "超过") {
+		t.Errorf("超大图片应返回大小错误，实际: %v", err)
+	}
+}
+
+func TestSendImageFile(t *testing.T) {
+	mock := newMockWeCom(t)
+	data := makePNG(t, 8, 8)
+	filePath := filepath.Join(t.TempDir(), "chart.png")
+	if err := os.WriteFile(filePath, data, 0o644); err != nil {
+		t.Fatalf("写入图片失败: %v", err)
+	}
+
+	if _, err := mock.client().SendImageFile(context.Background(), filePath); err != nil {
+		t.Fatalf("SendImageFile failed: %v", err)
+	}
+	image, _ := mock.lastPayload(t)["image"].(map[string]interface{})
+	if image["md5"] != calcMD5(data) {
+		t.Errorf("md5 = %v, want %s", image["md5"], calcMD5(data))
+	}
+}
+
+func TestSendImageURL(t *testing.T) {
+	data := makePNG(t, 8, 8)
+	images := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chart.png" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	t.Cleanup(images.Close)
+
+	mock := newMockWeCom(t)
+	client := mock.client()
+	// 测试图片服务器监听在回环地址
+	client.imageAddrAllowed = func(netip.Addr) bool { return true }
+	if _, err := client.SendImageURL(context.Background(), images.URL+"/chart.png"); err != nil {
+		t.Fatalf("SendImageURL failed: %v", err)
+	}
+	image, _ := mock.lastPayload(t)["image"].(map[string]interface{})
+	if image["md5"] != calcMD5(data) {
+		t.Errorf("md5 = %v, want %s", image["md5"], calcMD5(data))
+	}
+
+	if _, err := client.SendImageURL(context.Background(), images.URL+"/missing.png"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("图片不存在时应返回 HTTP 错误，实际: %v", err)
+	}
+}
+
+func TestFetchImageRejectsInternalAddress(t *testing.T) {
+	images := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 重定向到另一个回环地址
+		http.Redirect(w, r, "http://[::1]:1/latest/meta-data", http.StatusFound)
+	}))
+	t.Cleanup(images.Close)
+
+	client := NewClient(testWebhookKey)
+	for _, imageURL := range []string{images.URL + "/chart.png", "http://169.254.169.254/latest/meta-data", "http://100.100.100.200/"} {
+		if _, err := client.FetchImage(context.Background(), imageURL); err == nil || !strings.Contains(err.Error(), "不允许") {
+			t.Errorf("FetchImage(%s) = %v，期望拒绝访问内网地址", imageURL, err)
+		}
+	}
+	for _, imageURL := range []string{"file:///etc/passwd", "ftp://example.com/a.png", "http:///a.png"} {
+		if _, err := client.FetchImage(context.Background(), imageURL); err == nil {
+			t.Errorf("FetchImage(%s) 应返回错误", imageURL)
+		}
+	}
+
+	// 只允许连接 127.0.0.1 时，重定向后的地址同样会被检查
+	client.imageAddrAllowed = func(addr netip.Addr) bool { return addr == netip.MustParseAddr("127.0.0.1") }
+	if _, err := client.FetchImage(context.Background(), images.URL+"/chart.png"); err == nil || !strings.Contains(err.Error(), "::1") {
+		t.Errorf("重定向到内网地址时应返回错误，实际: %v", err)
+	}
+}
+
+func TestIsPublicAddr(t *testing.T) {
+	tests := map[string]bool{
+		"203.0.113.10":    true,
+		"2001:4860::8888": true,
+		"127.0.0.1":       false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"100.100.100.200": false,
+		"0.0.0.0":         false,
+		"::1":             false,
+		"fe80::1":         false,
+		"fd00::1":         false,
+		"::ffff:10.0.0.1": false,
+		"64:ff9b::a00:1":  false,
+	}
+	for addr, want := range tests {
+		if got := IsPublicAddr(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("IsPublicAddr(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestSendImageReaderTooLarge(t *testing.T) {
+	mock := newMockWeCom(t)
+	r := io.MultiReader(strings.NewReader("\xff\xd8\xff"), bytes.NewReader(make([]byte, MaxImageSourceBytes)))
+	if _, err := mock.client().SendImageReader(context.Background(), r); err == nil || !strings.Contains(err.Error(), "预处理上限") {
+		t.Fatalf("超大图片应返回错误，实际: %v", err)
+	}
+}
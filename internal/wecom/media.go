@@ -0,0 +1,189 @@
+package wecom
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// MediaExpiry 企业微信临时素材的有效期
+const MediaExpiry = 3 * 24 * time.Hour
+
+// MediaType 上传素材的类型
+type MediaType string
+
+// 素材类型
+const (
+	// MediaTypeFile 普通文件
+	MediaTypeFile MediaType = "file"
+	// MediaTypeVoice 语音，仅支持 AMR 格式
+	MediaTypeVoice MediaType = "voice"
+)
+
+// ParseMediaType 解析素材类型，空字符串视为普通文件
+func ParseMediaType(value string) (MediaType, error) {
+	switch MediaType(value) {
+	case "", MediaTypeFile:
+		return MediaTypeFile, nil
+	case MediaTypeVoice:
+		return MediaTypeVoice, nil
+	}
+	return "", fmt.Errorf("不支持的素材类型: %q，可选值为 file、voice", value)
+}
+
+// Media 上传成功的临时素材
+type Media struct {
+	// ID 媒体文件ID，用于发送文件等消息
+	ID string
+	// Type 媒体文件类型
+	Type string
+	// CreatedAt 上传时间
+	CreatedAt time.Time
+	// Cached 是否复用了素材缓存中相同内容的媒体ID，未实际上传
+	Cached bool
+}
+
+// ExpiresAt 返回素材的过期时间
+func (m *Media) ExpiresAt() time.Time {
+	return m.CreatedAt.Add(MediaExpiry)
+}
+
+// UploadMedia 上传本地文件，返回包含媒体ID和上传时间的素材信息
+func (c *Client) UploadMedia(ctx context.Context, filePath string, mediaType MediaType) (*Media, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("打开文件失败: %w", err)
+	}
+	defer file.Close()
+
+	// 语音需要读取全部内容校验格式和时长，先检查大小避免读入过大的文件
+	if mediaType == MediaTypeVoice {
+		info, err := file.Stat()
+		if err != nil {
+			return nil, fmt.Errorf("读取文件信息失败: %w", err)
+		}
+		if info.Size() > MaxVoiceBytes {
+			return nil, fmt.Errorf("语音文件大小为 %d 字节，超过 %d 字节限制", info.Size(), MaxVoiceBytes)
+		}
+	}
+
+	return c.UploadReader(ctx, filepath.Base(filePath), file, mediaType)
+}
+
+// UploadBytes 上传内存中的文件内容，filename 为群聊中显示的文件名
+func (c *Client) UploadBytes(ctx context.Context, filename string, data []byte, mediaType MediaType) (*Media, error) {
+	return c.UploadReader(ctx, filename, bytes.NewReader(data), mediaType)
+}
+
+// UploadReader 上传从 r 读取的文件内容，name 为群聊中显示的文件名，
+// 适用于远程调用方自行生成、服务器本地不存在的文件
+func (c *Client) UploadReader(ctx context.Context, name string, r io.Reader, mediaType MediaType) (*Media, error) {
+	if name == "" {
+		return nil, fmt.Errorf("文件名不能为空")
+	}
+
+	// 语音需要读取全部内容校验格式和时长
+	if mediaType == MediaTypeVoice {
+		data, err := io.ReadAll(io.LimitReader(r, MaxVoiceBytes+1))
+		if err != nil {
+			return nil, fmt.Errorf("读取文件失败: %w", err)
+		}
+		if err := validateVoice(data); err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(data)
+	}
+
+	return c.upload(ctx, name, r, mediaType)
+}
+
+// upload 以流式 multipart 表单上传文件内容
+func (c *Client) upload(ctx context.Context, filename string, r io.Reader, mediaType MediaType) (*Media, error) {
+	body, err := newMultipartBody(filepath.Base(filename), r, mediaType)
+	if err != nil {
+		return nil, err
+	}
+
+	// 相同内容在有效期内已上传过时直接复用
+	var sum string
+	var size int64
+	if c.mediaCache != nil {
+		var ok bool
+		sum, size, ok, err = hashSource(r)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			if entry, hit := c.mediaCache.Get(c.webhookKey, mediaType, body.filename, sum); hit {
+				return &Media{ID: entry.MediaID, Type: string(entry.MediaType), CreatedAt: entry.CreatedAt, Cached: true}, nil
+			}
+		}
+	}
+
+	uploadURL := c.endpoint("upload_media", url.Values{"type": {string(mediaType)}})
+	result, err := c.do(ctx, uploadURL, body.requestBody())
+	// 读取文件失败或大小超限时，返回比网络错误更明确的原因
+	if bodyErr := body.wait(); bodyErr != nil {
+		return nil, bodyErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if result.MediaID == "" {
+		return nil, fmt.Errorf("无法获取媒体ID")
+	}
+
+	media := &Media{
+		ID:        result.MediaID,
+		Type:      result.Type,
+		CreatedAt: parseCreatedAt(result.CreatedAt),
+	}
+	if sum != "" {
+		// 缓存写入失败不影响本次上传结果
+		_ = c.mediaCache.Put(c.webhookKey, CachedMedia{
+			MediaID:   media.ID,
+			MediaType: mediaType,
+			Filename:  body.filename,
+			SHA256:    sum,
+			Size:      size,
+			CreatedAt: media.CreatedAt,
+			ExpiresAt: media.ExpiresAt(),
+		})
+	}
+	return media, nil
+}
+
+// parseCreatedAt 解析秒级时间戳，无法解析时使用当前时间
+func parseCreatedAt(value string) time.Time {
+	if sec, err := strconv.ParseInt(value, 10, 64); err == nil && sec > 0 {
+		return time.Unix(sec, 0)
+	}
+	return time.Now()
+}
+
+// SendFile 发送文件消息
+func (c *Client) SendFile(mediaID string) error {
+	return c.SendFileContext(context.Background(), mediaID)
+}
+
+// SendFileContext 使用指定上下文发送文件消息
+func (c *Client) SendFileContext(ctx context.Context, mediaID string) error {
+	return c.Send(ctx, &FileMessage{MediaID: mediaID})
+}
+
+// SendVoice 发送语音消息
+func (c *Client) SendVoice(mediaID string) error {
+	return c.SendVoiceContext(context.Background(), mediaID)
+}
+
+// SendVoiceContext 使用指定上下文发送语音消息
+func (c *Client) SendVoiceContext(ctx context.Context, mediaID string) error {
+	return c.Send(ctx, &VoiceMessage{MediaID: mediaID})
+}
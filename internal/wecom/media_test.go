@@ -0,0 +1,66 @@
+package wecom
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUploadBytes(t *testing.T) {
+	mock := newMockWeCom(t)
+	media, err := mock.client().UploadBytes(context.Background(), "report.txt", []byte("hello wecom"), MediaTypeFile)
+	if err != nil {
+		t.Fatalf("UploadBytes failed: %v", err)
+	}
+	if media.ID != "test-media-id" || media.Type != "file" {
+		t.Errorf("media = %+v", media)
+	}
+	if want := time.Unix(1380000000, 0).Add(MediaExpiry); !media.ExpiresAt().Equal(want) {
+		t.Errorf("ExpiresAt = %v, want %v", media.ExpiresAt(), want)
+	}
+
+	req := mock.lastRequest(t)
+	if !strings.Contains(string(req.Body), `filename="report.txt"`) {
+		t.Errorf("上传请求缺少文件名: %s", req.Body)
+	}
+}
+
+func TestUploadReader(t *testing.T) {
+	mock := newMockWeCom(t)
+	client := mock.client()
+
+	media, err := client.UploadReader(context.Background(), "reports/daily.csv", strings.NewReader("a,b\n1,2\n"), MediaTypeFile)
+	if err != nil {
+		t.Fatalf("UploadReader failed: %v", err)
+	}
+	if media.ID != "test-media-id" {
+		t.Errorf("media = %+v", media)
+	}
+	req := mock.lastRequest(t)
+	if !strings.Contains(string(req.Body), `filename="daily.csv"`) || !strings.Contains(string(req.Body), "a,b\n1,2\n") {
+		t.Errorf("上传请求内容不正确: %s", req.Body)
+	}
+
+	if _, err := client.UploadReader(context.Background(), "voice.amr", bytes.NewReader(makeAMR(50)), MediaTypeVoice); err != nil {
+		t.Errorf("上传语音失败: %v", err)
+	}
+	if _, err := client.UploadReader(context.Background(), "voice.amr", strings.NewReader("not amr"), MediaTypeVoice); err == nil {
+		t.Error("非 AMR 语音应返回错误")
+	}
+	if _, err := client.UploadReader(context.Background(), "", strings.NewReader("data"), MediaTypeFile); err == nil {
+		t.Error("文件名为空时应返回错误")
+	}
+}
+
+func TestSendFile(t *testing.T) {
+	mock := newMockWeCom(t)
+	if err := mock.client().SendFile("test-media-id"); err != nil {
+		t.Fatalf("SendFile failed: %v", err)
+	}
+	file, _ := mock.lastPayload(t)["file"].(map[string]interface{})
+	if file["media_id"] != "test-media-id" {
+		t.Errorf("file = %v", file)
+	}
+}
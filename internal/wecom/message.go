@@ -0,0 +1,228 @@
+package wecom
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+// 消息类型
+const (
+	MsgTypeText         = "text"
+	MsgTypeMarkdown     = "markdown"
+	MsgTypeImage        = "image"
+	MsgTypeNews         = "news"
+	MsgTypeTemplateCard = "template_card"
+	MsgTypeFile         = "file"
+	MsgTypeVoice        = "voice"
+)
+
+// 企业微信对消息内容的限制（按 UTF-8 字节计算）
+const (
+	// MaxTextBytes 文本消息内容最大字节数
+	MaxTextBytes = 2048
+	// MaxMarkdownBytes Markdown 消息内容最大字节数
+	MaxMarkdownBytes = 4096
+	// MaxNewsArticles 图文消息最多包含的文章数
+	MaxNewsArticles = 8
+	// MaxNewsTitleBytes 图文消息文章标题最大字节数
+	MaxNewsTitleBytes = 128
+	// MaxNewsDescriptionBytes 图文消息文章描述最大字节数
+	MaxNewsDescriptionBytes = 512
+)
+
+// Message 可通过机器人发送的消息
+type Message interface {
+	// MsgType 返回消息类型，对应请求体中的 msgtype 字段
+	MsgType() string
+	// Validate 在发送前校验消息内容
+	Validate() error
+}
+
+// ValidationError 消息校验失败的错误
+type ValidationError struct {
+	// Field 出错的字段路径，例如 "articles[1].title"
+	Field string
+	// Reason 出错原因
+	Reason string
+}
+
+// Error 实现 error 接口
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("消息校验失败: %s %s", e.Field, e.Reason)
+}
+
+// invalid 创建 ValidationError
+func invalid(field, format string, args ...interface{}) error {
+	return &ValidationError{Field: field, Reason: fmt.Sprintf(format, args...)}
+}
+
+// MarshalMessage 将消息序列化为企业微信请求体
+func MarshalMessage(m Message) ([]byte, error) {
+	msgType := m.MsgType()
+	return json.Marshal(map[string]interface{}{
+		"msgtype": msgType,
+		msgType:   m,
+	})
+}
+
+// TextMessage 文本消息
+type TextMessage struct {
+	Content string `json:"content"`
+	// MentionedList 要@的用户ID列表，"@all" 表示所有人
+	MentionedList []string `json:"mentioned_list,omitempty"`
+	// MentionedMobileList 要@的手机号列表，"@all" 表示所有人
+	MentionedMobileList []string `json:"mentioned_mobile_list,omitempty"`
+}
+
+// MsgType 实现 Message 接口
+func (m *TextMessage) MsgType() string { return MsgTypeText }
+
+// Validate 实现 Message 接口
+func (m *TextMessage) Validate() error {
+	if m.Content == "" {
+		return invalid("content", "不能为空")
+	}
+	if n := len(m.Content); n > MaxTextBytes {
+		return invalid("content", "长度为 %d 字节，超过 %d 字节限制", n, MaxTextBytes)
+	}
+	return nil
+}
+
+// MarkdownMessage Markdown 消息
+type MarkdownMessage struct {
+	Content string `json:"content"`
+}
+
+// MsgType 实现 Message 接口
+func (m *MarkdownMessage) MsgType() string { return MsgTypeMarkdown }
+
+// Validate 实现 Message 接口
+func (m *MarkdownMessage) Validate() error {
+	if m.Content == "" {
+		return invalid("content", "不能为空")
+	}
+	if n := len(m.Content); n > MaxMarkdownBytes {
+		return invalid("content", "长度为 %d 字节，超过 %d 字节限制", n, MaxMarkdownBytes)
+	}
+	return nil
+}
+
+// md5Pattern 32 位十六进制 MD5
+var md5Pattern = regexp.MustCompile(`^[0-9a-fA-F]{32}$`)
+
+// ImageMessage 图片消息
+type ImageMessage struct {
+	// Base64 图片内容的 Base64 编码
+	Base64 string `json:"base64"`
+	// MD5 图片内容（编码前）的 MD5 值
+	MD5 string `json:"md5"`
+}
+
+// MsgType 实现 Message 接口
+func (m *ImageMessage) MsgType() string { return MsgTypeImage }
+
+// Validate 实现 Message 接口
+func (m *ImageMessage) Validate() error {
+	if m.Base64 == "" {
+		return invalid("base64", "不能为空")
+	}
+	if !md5Pattern.MatchString(m.MD5) {
+		return invalid("md5", "必须是 32 位十六进制字符串")
+	}
+	return nil
+}
+
+// NewsArticle 新闻文章结构
+type NewsArticle struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	URL         string `json:"url"`
+	PicURL      string `json:"picurl,omitempty"`
+}
+
+// NewsMessage 图文消息
+type NewsMessage struct {
+	Articles []NewsArticle `json:"articles"`
+}
+
+// MsgType 实现 Message 接口
+func (m *NewsMessage) MsgType() string { return MsgTypeNews }
+
+// Validate 实现 Message 接口
+func (m *NewsMessage) Validate() error {
+	if n := len(m.Articles); n == 0 || n > MaxNewsArticles {
+		return invalid("articles", "数量为 %d，必须为 1~%d 篇", n, MaxNewsArticles)
+	}
+	for i, article := range m.Articles {
+		field := fmt.Sprintf("articles[%d]", i)
+		if article.Title == "" {
+			return invalid(field+".title", "不能为空")
+		}
+		if n := len(article.Title); n > MaxNewsTitleBytes {
+			return invalid(field+".title", "长度为 %d 字节，超过 %d 字节限制", n, MaxNewsTitleBytes)
+		}
+		if n := len(article.Description); n > MaxNewsDescriptionBytes {
+			return invalid(field+".description", "长度为 %d 字节，超过 %d 字节限制", n, MaxNewsDescriptionBytes)
+		}
+		if article.URL == "" {
+			return invalid(field+".url", "不能为空")
+		}
+	}
+	return nil
+}
+
+// FileMessage 文件消息
+type FileMessage struct {
+	// MediaID 通过上传接口获得的媒体文件ID
+	MediaID string `json:"media_id"`
+}
+
+// MsgType 实现 Message 接口
+func (m *FileMessage) MsgType() string { return MsgTypeFile }
+
+// Validate 实现 Message 接口
+func (m *FileMessage) Validate() error {
+	if m.MediaID == "" {
+		return invalid("media_id", "不能为空")
+	}
+	return nil
+}
+
+// VoiceMessage 语音消息
+type VoiceMessage struct {
+	// MediaID 通过上传接口以 voice 类型上传获得的媒体文件ID
+	MediaID string `json:"media_id"`
+}
+
+// MsgType 实现 Message 接口
+func (m *VoiceMessage) MsgType() string { return MsgTypeVoice }
+
+// Validate 实现 Message 接口
+func (m *VoiceMessage) Validate() error {
+	if m.MediaID == "" {
+		return invalid("media_id", "不能为空")
+	}
+	return nil
+}
+
+// TemplateCardMessage 模板卡片消息
+type TemplateCardMessage struct {
+	CardType   string         `json:"card_type"`
+	MainTitle  *CardMainTitle `json:"main_title,omitempty"`
+	CardAction *CardAction    `json:"card_action"`
+}
+
+// MsgType 实现 Message 接口
+func (m *TemplateCardMessage) MsgType() string { return MsgTypeTemplateCard }
+
+// Validate 实现 Message 接口
+func (m *TemplateCardMessage) Validate() error {
+	if m.CardType != CardTypeTextNotice && m.CardType != CardTypeNewsNotice {
+		return invalid("card_type", "必须为 %s 或 %s，实际为 %q", CardTypeTextNotice, CardTypeNewsNotice, m.CardType)
+	}
+	if m.CardAction == nil {
+		return invalid("card_action", "不能为空")
+	}
+	return m.CardAction.validate("card_action")
+}
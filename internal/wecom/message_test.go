@@ -0,0 +1,73 @@
+package wecom
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMarshalMessage(t *testing.T) {
+	data, err := MarshalMessage(&TextMessage{Content: "hello"})
+	if err != nil {
+		t.Fatalf("MarshalMessage failed: %v", err)
+	}
+	want := `{"msgtype":"text","text":{"content":"hello"}}`
+	if string(data) != want {
+		t.Errorf("MarshalMessage = %s, want %s", data, want)
+	}
+
+	data, err = MarshalMessage(&NewsMessage{Articles: []NewsArticle{{Title: "t", URL: "https://example.com"}}})
+	if err != nil {
+		t.Fatalf("MarshalMessage failed: %v", err)
+	}
+	want = `{"msgtype":"news","news":{"articles":[{"title":"t","url":"https://example.com"}]}}`
+	if string(data) != want {
+		t.Errorf("MarshalMessage = %s, want %s", data, want)
+	}
+}
+
+func TestMessageValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		msg   Message
+		field string
+	}{
+		{"empty text", &TextMessage{}, "content"},
+		{"text too long", &TextMessage{Content: strings.Repeat("中", 700)}, "content"},
+		{"markdown ok", &MarkdownMessage{Content: strings.Repeat("中", 1000)}, ""},
+		{"bad md5", &ImageMessage{Base64: "aGVsbG8=", MD5: "xyz"}, "md5"},
+		{"no articles", &NewsMessage{}, "articles"},
+		{"too many articles", &NewsMessage{Articles: make([]NewsArticle, MaxNewsArticles+1)}, "articles"},
+		{"article title too long", &NewsMessage{Articles: []NewsArticle{{Title: strings.Repeat("标", 43), URL: "u"}}}, "articles[0].title"},
+		{"article description too long", &NewsMessage{Articles: []NewsArticle{{Title: "t", URL: "u", Description: strings.Repeat("a", 513)}}}, "articles[0].description"},
+		{"article without url", &NewsMessage{Articles: []NewsArticle{{Title: "a", URL: "u"}, {Title: "b"}}}, "articles[1].url"},
+		{"card without action", &TemplateCardMessage{CardType: CardTypeTextNotice}, "card_action"},
+		{"card bad action", &TemplateCardMessage{CardType: CardTypeTextNotice, CardAction: &CardAction{Type: 1}}, "card_action.url"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.msg.Validate()
+			if tt.field == "" {
+				if err != nil {
+					t.Fatalf("期望校验通过，实际: %v", err)
+				}
+				return
+			}
+			var vErr *ValidationError
+			if !errors.As(err, &vErr) || vErr.Field != tt.field {
+				t.Fatalf("期望字段 %s 校验失败，实际: %v", tt.field, err)
+			}
+		})
+	}
+}
+
+func TestSendInvalidMessageSkipsRequest(t *testing.T) {
+	mock := newMockWeCom(t)
+	if err := mock.client().Send(context.Background(), &TextMessage{}); err == nil {
+		t.Fatalf("期望返回校验错误")
+	}
+	if len(mock.requests) != 0 {
+		t.Errorf("校验失败时不应发出请求")
+	}
+}
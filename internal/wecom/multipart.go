@@ -0,0 +1,198 @@
+package wecom
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"os"
+)
+
+// 企业微信对上传文件的大小限制
+const (
+	// MaxFileBytes 普通文件最大字节数
+	MaxFileBytes = 20 << 20
+	// MinFileBytes 上传文件最小字节数
+	MinFileBytes = 5
+)
+
+// maxUploadBytes 返回素材类型允许的最大字节数
+func maxUploadBytes(mediaType MediaType) int64 {
+	if mediaType == MediaTypeVoice {
+		return MaxVoiceBytes
+	}
+	return MaxFileBytes
+}
+
+// checkUploadSize 检查上传文件的大小是否在企业微信允许的范围内
+func checkUploadSize(size int64, mediaType MediaType) error {
+	if size < MinFileBytes {
+		return fmt.Errorf("文件大小为 %d 字节，不能小于 %d 字节", size, MinFileBytes)
+	}
+	if limit := maxUploadBytes(mediaType); size > limit {
+		return fmt.Errorf("文件大小为 %d 字节，超过 %d 字节限制", size, limit)
+	}
+	return nil
+}
+
+// readerSize 返回 r 剩余可读取的字节数，无法确定时返回 -1
+func readerSize(r io.Reader) int64 {
+	switch v := r.(type) {
+	case interface{ Len() int }:
+		// bytes.Reader、strings.Reader、bytes.Buffer
+		return int64(v.Len())
+	case *os.File:
+		info, err := v.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			return -1
+		}
+		offset, err := v.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return -1
+		}
+		return info.Size() - offset
+	}
+	return -1
+}
+
+// multipartBody 通过 io.Pipe 边读边发送的 multipart 表单，不在内存中缓存整个文件。
+// src 实现 io.Seeker 时重试会从起始位置重新读取，否则只能发送一次。
+type multipartBody struct {
+	filename string
+	src      io.Reader
+	// size 文件字节数，未知时为 -1
+	size     int64
+	limit    int64
+	boundary string
+	// start src 的起始读取位置
+	start int64
+
+	// done 上一次写入请求体的协程结束时关闭
+	done chan struct{}
+	// err 读取源文件失败或大小不符合限制时的错误，在 done 关闭后可读
+	err error
+}
+
+// newMultipartBody 创建上传请求体，大小已知时在发送前检查大小限制
+func newMultipartBody(filename string, src io.Reader, mediaType MediaType) (*multipartBody, error) {
+	b := &multipartBody{
+		filename: filename,
+		src:      src,
+		size:     readerSize(src),
+		limit:    maxUploadBytes(mediaType),
+		boundary: multipart.NewWriter(io.Discard).Boundary(),
+	}
+	if b.size >= 0 {
+		if err := checkUploadSize(b.size, mediaType); err != nil {
+			return nil, err
+		}
+	}
+	if seeker, ok := src.(io.Seeker); ok {
+		if offset, err := seeker.Seek(0, io.SeekCurrent); err == nil {
+			b.start = offset
+		}
+	}
+	return b, nil
+}
+
+// requestBody 转换为通用请求体，文件大小已知时设置 Content-Length
+func (b *multipartBody) requestBody() requestBody {
+	length := int64(-1)
+	if b.size >= 0 {
+		// 表单头尾的长度与文件内容无关，预先写入一份空表单计算
+		var overhead bytes.Buffer
+		writer := multipart.NewWriter(&overhead)
+		writer.SetBoundary(b.boundary)
+		writer.CreateFormFile("media", b.filename)
+		writer.Close()
+		length = int64(overhead.Len()) + b.size
+	}
+	return requestBody{
+		contentType: "multipart/form-data; boundary=" + b.boundary,
+		length:      length,
+		open:        b.open,
+		resendable:  true,
+	}
+}
+
+// open 启动协程向管道写入表单，重试时先等待上一次写入结束再重新定位源文件
+func (b *multipartBody) open() (io.ReadCloser, error) {
+	if b.done != nil {
+		<-b.done
+		if b.err != nil {
+			return nil, b.err
+		}
+		seeker, ok := b.src.(io.Seeker)
+		if !ok {
+			return nil, errBodyNotReplayable
+		}
+		if _, err := seeker.Seek(b.start, io.SeekStart); err != nil {
+			return nil, errBodyNotReplayable
+		}
+	}
+
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	b.done = done
+	go func() {
+		defer close(done)
+		pw.CloseWithError(b.write(pw))
+	}()
+	return pr, nil
+}
+
+// wait 等待写入协程结束，返回读取源文件时的错误
+func (b *multipartBody) wait() error {
+	if b.done == nil {
+		return nil
+	}
+	<-b.done
+	return b.err
+}
+
+// write 写入完整的 multipart 表单
+func (b *multipartBody) write(w io.Writer) error {
+	writer := multipart.NewWriter(w)
+	writer.SetBoundary(b.boundary)
+
+	part, err := writer.CreateFormFile("media", b.filename)
+	if err != nil {
+		return err
+	}
+
+	src := &countingReader{r: io.LimitReader(b.src, b.limit+1)}
+	_, err = io.Copy(part, src)
+	switch {
+	case src.err != nil:
+		b.err = fmt.Errorf("读取文件失败: %w", src.err)
+		return b.err
+	case err != nil:
+		// 请求已中止，管道被关闭
+		return err
+	case src.n > b.limit:
+		b.err = fmt.Errorf("文件大小超过 %d 字节限制", b.limit)
+		return b.err
+	case src.n < MinFileBytes:
+		b.err = fmt.Errorf("文件大小为 %d 字节，不能小于 %d 字节", src.n, MinFileBytes)
+		return b.err
+	}
+
+	return writer.Close()
+}
+
+// countingReader 统计读取的字节数并记录读取错误
+type countingReader struct {
+	r   io.Reader
+	n   int64
+	err error
+}
+
+// Read 实现 io.Reader 接口
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	if err != nil && err != io.EOF {
+		c.err = err
+	}
+	return n, err
+}
@@ -0,0 +1,145 @@
+package wecom
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// uploadRecorder 记录上传请求的长度和文件内容
+type uploadRecorder struct {
+	mu       sync.Mutex
+	lengths  []int64
+	contents []string
+	// failFirst 为 true 时第一次请求返回 HTTP 500
+	failFirst bool
+}
+
+func (u *uploadRecorder) handler(t *testing.T) func(w http.ResponseWriter, r *http.Request, body []byte) {
+	return func(w http.ResponseWriter, r *http.Request, body []byte) {
+		_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil {
+			t.Errorf("解析 Content-Type 失败: %v", err)
+		}
+		form, err := multipart.NewReader(bytes.NewReader(body), params["boundary"]).ReadForm(MaxFileBytes)
+		if err != nil {
+			t.Errorf("解析表单失败: %v", err)
+			return
+		}
+		file, _ := form.File["media"][0].Open()
+		content, _ := io.ReadAll(file)
+
+		u.mu.Lock()
+		u.lengths = append(u.lengths, r.ContentLength)
+		u.contents = append(u.contents, string(content))
+		fail := u.failFirst && len(u.lengths) == 1
+		u.mu.Unlock()
+
+		if fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		io.WriteString(w, `{"errcode":0,"errmsg":"ok","type":"file","media_id":"test-media-id","created_at":"1380000000"}`)
+	}
+}
+
+func TestUploadStreamsWithContentLength(t *testing.T) {
+	mock := newMockWeCom(t)
+	rec := &uploadRecorder{failFirst: true}
+	mock.handler = rec.handler(t)
+
+	filePath := filepath.Join(t.TempDir(), "report.txt")
+	content := strings.Repeat("daily report\n", 1000)
+	if err := os.WriteFile(filePath, []byte(content), 0o644); err != nil {
+		t.Fatalf("写入文件失败: %v", err)
+	}
+
+	client := mock.client(WithRetryPolicy(fastRetry))
+	if _, err := client.UploadMedia(context.Background(), filePath, MediaTypeFile); err != nil {
+		t.Fatalf("UploadMedia failed: %v", err)
+	}
+
+	// 第一次请求失败后从文件开头重新发送
+	if len(rec.contents) != 2 {
+		t.Fatalf("期望发送 2 次请求，实际 %d 次", len(rec.contents))
+	}
+	for i, got := range rec.contents {
+		if got != content {
+			t.Errorf("第 %d 次请求的文件内容长度 %d，期望 %d", i+1, len(got), len(content))
+		}
+		if rec.lengths[i] <= int64(len(content)) {
+			t.Errorf("第 %d 次请求 Content-Length = %d", i+1, rec.lengths[i])
+		}
+	}
+}
+
+func TestUploadUnknownSizeReader(t *testing.T) {
+	mock := newMockWeCom(t)
+	rec := &uploadRecorder{failFirst: true}
+	mock.handler = rec.handler(t)
+	client := mock.client(WithRetryPolicy(fastRetry))
+
+	// 无法确定大小的 reader 使用分块传输，且无法重新读取，失败后不重试
+	r := io.MultiReader(strings.NewReader("hello "), strings.NewReader("wecom"))
+	if _, err := client.UploadReader(context.Background(), "hello.txt", r, MediaTypeFile); err == nil {
+		t.Fatal("无法重新读取的请求体失败后应返回错误")
+	}
+	if len(rec.lengths) != 1 || rec.lengths[0] != -1 || rec.contents[0] != "hello wecom" {
+		t.Errorf("lengths = %v, contents = %q", rec.lengths, rec.contents)
+	}
+
+	rec.failFirst = false
+	r = io.MultiReader(strings.NewReader("hello "), strings.NewReader("wecom"))
+	if _, err := client.UploadReader(context.Background(), "hello.txt", r, MediaTypeFile); err != nil {
+		t.Fatalf("UploadReader failed: %v", err)
+	}
+}
+
+func TestUploadSizeLimits(t *testing.T) {
+	mock := newMockWeCom(t)
+	client := mock.client(WithRetryPolicy(NoRetry))
+
+	if _, err := client.UploadBytes(context.Background(), "tiny.txt", []byte("abc"), MediaTypeFile); err == nil || !strings.Contains(err.Error(), "不能小于") {
+		t.Errorf("小于 5 字节的文件应返回错误，实际: %v", err)
+	}
+
+	filePath := filepath.Join(t.TempDir(), "large.bin")
+	file, err := os.Create(filePath)
+	if err != nil {
+		t.Fatalf("创建文件失败: %v", err)
+	}
+	file.Truncate(MaxFileBytes + 1)
+	file.Close()
+	if _, err := client.UploadMedia(context.Background(), filePath, MediaTypeFile); err == nil || !strings.Contains(err.Error(), "超过") {
+		t.Errorf("超过 20MB 的文件应返回错误，实际: %v", err)
+	}
+
+	mock.mu.Lock()
+	requests := len(mock.requests)
+	mock.mu.Unlock()
+	if requests != 0 {
+		t.Errorf("大小检查失败时不应发送请求，实际发送 %d 次", requests)
+	}
+
+	// 大小未知时在读取过程中检查
+	r := io.MultiReader(strings.NewReader("x"), io.LimitReader(zeroReader{}, MaxFileBytes))
+	if _, err := client.UploadReader(context.Background(), "large.bin", r, MediaTypeFile); err == nil || !strings.Contains(err.Error(), "超过") {
+		t.Errorf("超过 20MB 的流应返回错误，实际: %v", err)
+	}
+}
+
+// zeroReader 无限输出 0 的 reader
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
@@ -0,0 +1,54 @@
+package wecom
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultUserAgent 默认的 User-Agent 请求头
+const DefaultUserAgent = "wecom-bot-server-go"
+
+// Option 客户端配置选项
+type Option func(*Client)
+
+// WithBaseURL 设置企业微信机器人接口地址，用于私有化部署、出口代理或本地模拟服务
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		if baseURL != "" {
+			c.baseURL = strings.TrimRight(baseURL, "/")
+		}
+	}
+}
+
+// WithHTTPClient 使用自定义的 HTTP 客户端
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		if httpClient != nil {
+			c.httpClient = httpClient
+		}
+	}
+}
+
+// WithTransport 设置底层 RoundTripper，不会修改 WithHTTPClient 传入的客户端
+func WithTransport(transport http.RoundTripper) Option {
+	return func(c *Client) {
+		c.transport = transport
+	}
+}
+
+// WithTimeout 设置单次 HTTP 请求的超时时间
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+// WithUserAgent 设置请求的 User-Agent
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		if userAgent != "" {
+			c.userAgent = userAgent
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package wecom
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+
+	_ "golang.org/x/image/bmp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// 图片预处理的限制
+const (
+	// MaxImageSourceBytes 允许预处理的原图最大字节数
+	MaxImageSourceBytes = 20 << 20
+	// MaxImageSourcePixels 允许预处理的原图最大像素数。解码后每个像素占 4 字节，缩放时还需要额外的缓冲区，
+	// 1600 万像素约占用 64MB，已足够压缩出 2MB 以内的图片
+	MaxImageSourcePixels = 16_000_000
+	// maxConcurrentPrepares 同时解码和压缩的图片数量上限，限制并发调用占用的内存
+	maxConcurrentPrepares = 2
+	// minImageSide 缩小图片时的最小边长，仍无法满足大小限制时放弃
+	minImageSide = 64
+)
+
+// prepareSlots 限制同时解码的图片数量
+var prepareSlots = make(chan struct{}, maxConcurrentPrepares)
+
+// imageScaleStep 每轮缩小图片的比例
+const imageScaleStep = 0.75
+
+// jpegQualities 重新编码为 JPEG 时依次尝试的质量
+var jpegQualities = []int{90, 80, 70, 60}
+
+// sourceFormats 预处理支持的输入格式，对应 image 包注册的格式名称
+var sourceFormats = map[string]bool{
+	"jpeg": true,
+	"png":  true,
+	"gif":  true,
+	"webp": true,
+	"bmp":  true,
+}
+
+// PreparedImage 预处理后可直接发送的图片
+type PreparedImage struct {
+	// Data 图片内容，JPG 或 PNG 格式且不超过 MaxImageBytes
+	Data []byte
+	// Format 图片格式，ImageFormatJPEG 或 ImageFormatPNG
+	Format string
+	// Width、Height 图片尺寸
+	Width, Height int
+
+	// SourceFormat、SourceSize 原图的格式和字节数
+	SourceFormat string
+	SourceSize   int
+	// SourceWidth、SourceHeight 原图尺寸
+	SourceWidth, SourceHeight int
+}
+
+// Converted 返回图片是否经过重新编码或缩放
+func (p *PreparedImage) Converted() bool {
+	return p.SourceSize != len(p.Data) || p.SourceFormat != p.Format
+}
+
+// PrepareImage 将图片转换为企业微信支持的格式并控制在 2MB 以内。
+// 符合要求的 JPG、PNG 原样返回；GIF（仅取第一帧）、WebP、BMP 先转换为 PNG，
+// 仍然过大时依次降低 JPEG 质量、按比例缩小尺寸，直到满足大小限制。
+func PrepareImage(data []byte) (*PreparedImage, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("图片内容为空")
+	}
+	if len(data) > MaxImageSourceBytes {
+		return nil, fmt.Errorf("图片大小为 %d 字节，超过预处理上限 %d 字节", len(data), MaxImageSourceBytes)
+	}
+
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || !sourceFormats[format] {
+		return nil, fmt.Errorf("图片格式不支持，仅支持 JPG、PNG、GIF、WebP 和 BMP")
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > MaxImageSourcePixels {
+		return nil, fmt.Errorf("图片尺寸 %dx%d 超出处理范围", cfg.Width, cfg.Height)
+	}
+
+	prepared := &PreparedImage{
+		Data:         data,
+		Format:       format,
+		Width:        cfg.Width,
+		Height:       cfg.Height,
+		SourceFormat: format,
+		SourceSize:   len(data),
+		SourceWidth:  cfg.Width,
+		SourceHeight: cfg.Height,
+	}
+	if (format == ImageFormatJPEG || format == ImageFormatPNG) && len(data) <= MaxImageBytes {
+		return prepared, nil
+	}
+
+	prepareSlots <- struct{}{}
+	defer func() { <-prepareSlots }()
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("解码图片失败: %w", err)
+	}
+
+	// 非 JPEG 原图优先无损转换为 PNG，保留透明度和文字清晰度
+	if format != ImageFormatJPEG {
+		encoded, err := encodeImage(img, ImageFormatPNG, 0)
+		if err != nil {
+			return nil, err
+		}
+		if len(encoded) <= MaxImageBytes {
+			return prepared.with(encoded, ImageFormatPNG, img), nil
+		}
+	}
+
+	for {
+		for _, quality := range jpegQualities {
+			encoded, err := encodeImage(img, ImageFormatJPEG, quality)
+			if err != nil {
+				return nil, err
+			}
+			if len(encoded) <= MaxImageBytes {
+				return prepared.with(encoded, ImageFormatJPEG, img), nil
+			}
+		}
+
+		bounds := img.Bounds()
+		width := int(float64(bounds.Dx()) * imageScaleStep)
+		height := int(float64(bounds.Dy()) * imageScaleStep)
+		if width < minImageSide || height < minImageSide {
+			return nil, fmt.Errorf("图片缩小到 %dx%d 后仍超过 %d 字节限制", bounds.Dx(), bounds.Dy(), MaxImageBytes)
+		}
+		img = resizeImage(img, width, height)
+	}
+}
+
+// with 返回使用新图片内容的副本
+func (p *PreparedImage) with(data []byte, format string, img image.Image) *PreparedImage {
+	prepared := *p
+	prepared.Data = data
+	prepared.Format = format
+	prepared.Width = img.Bounds().Dx()
+	prepared.Height = img.Bounds().Dy()
+	return &prepared
+}
+
+// encodeImage 将图片编码为 PNG 或指定质量的 JPEG
+func encodeImage(img image.Image, format string, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	if format == ImageFormatPNG {
+		encoder := png.Encoder{CompressionLevel: png.BestCompression}
+		err = encoder.Encode(&buf, img)
+	} else {
+		// JPEG 不支持透明度，透明区域以白色背景填充
+		err = jpeg.Encode(&buf, flatten(img), &jpeg.Options{Quality: quality})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("编码图片失败: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// flatten 将带透明度的图片合成到白色背景上
+func flatten(img image.Image) image.Image {
+	if opaque, ok := img.(interface{ Opaque() bool }); ok && opaque.Opaque() {
+		return img
+	}
+	bounds := img.Bounds()
+	dst := image.NewRGBA(bounds)
+	draw.Draw(dst, bounds, image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, bounds, img, bounds.Min, draw.Over)
+	return dst
+}
+
+// resizeImage 将图片缩放到指定尺寸
+func resizeImage(img image.Image, width, height int) image.Image {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Over, nil)
+	return dst
+}
@@ -0,0 +1,96 @@
+package wecom
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// tinyWebP 1x1 的无损 WebP 图片
+const tinyWebP = "UklGRhoAAABXRUJQVlA4TA0AAAAvAAAAEAcQERGIiP4HAA=="
+
+func TestPrepareImageKeepsSupportedImage(t *testing.T) {
+	data := makePNG(t, 32, 16)
+	prepared, err := PrepareImage(data)
+	if err != nil {
+		t.Fatalf("PrepareImage failed: %v", err)
+	}
+	if prepared.Converted() || !bytes.Equal(prepared.Data, data) {
+		t.Errorf("符合要求的图片不应重新编码: %+v", prepared)
+	}
+	if prepared.Width != 32 || prepared.Height != 16 || prepared.Format != ImageFormatPNG {
+		t.Errorf("prepared = %dx%d %s", prepared.Width, prepared.Height, prepared.Format)
+	}
+}
+
+func TestPrepareImageConvertsFormats(t *testing.T) {
+	var buf bytes.Buffer
+	palette := color.Palette{color.Black, color.White}
+	if err := gif.Encode(&buf, image.NewPaletted(image.Rect(0, 0, 20, 10), palette), nil); err != nil {
+		t.Fatalf("生成 GIF 失败: %v", err)
+	}
+	webp, _ := base64.StdEncoding.DecodeString(tinyWebP)
+
+	for name, data := range map[string][]byte{"gif": buf.Bytes(), "webp": webp} {
+		prepared, err := PrepareImage(data)
+		if err != nil {
+			t.Fatalf("%s: PrepareImage failed: %v", name, err)
+		}
+		if prepared.SourceFormat != name || prepared.Format != ImageFormatPNG || DetectImageFormat(prepared.Data) != ImageFormatPNG {
+			t.Errorf("%s: prepared = %s -> %s", name, prepared.SourceFormat, prepared.Format)
+		}
+		if !prepared.Converted() {
+			t.Errorf("%s: Converted() = false", name)
+		}
+	}
+}
+
+func TestPrepareImageShrinksLargeImage(t *testing.T) {
+	// 随机噪点几乎无法压缩，PNG 编码后远超 2MB
+	rng := rand.New(rand.NewSource(1))
+	img := image.NewNRGBA(image.Rect(0, 0, 1200, 1000))
+	rng.Read(img.Pix)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("生成 PNG 失败: %v", err)
+	}
+	if buf.Len() <= MaxImageBytes {
+		t.Fatalf("测试图片大小 %d 未超过限制", buf.Len())
+	}
+
+	prepared, err := PrepareImage(buf.Bytes())
+	if err != nil {
+		t.Fatalf("PrepareImage failed: %v", err)
+	}
+	if len(prepared.Data) > MaxImageBytes || prepared.Format != ImageFormatJPEG {
+		t.Errorf("prepared = %d 字节 %s", len(prepared.Data), prepared.Format)
+	}
+	if prepared.SourceWidth != 1200 || prepared.SourceHeight != 1000 || prepared.Width > 1200 {
+		t.Errorf("prepared = %dx%d -> %dx%d", prepared.SourceWidth, prepared.SourceHeight, prepared.Width, prepared.Height)
+	}
+	if _, err := NewImageMessage(prepared.Data); err != nil {
+		t.Errorf("预处理后的图片仍无法发送: %v", err)
+	}
+}
+
+func TestPrepareImageRejectsUnknownFormat(t *testing.T) {
+	if _, err := PrepareImage([]byte("not an image")); err == nil {
+		t.Error("无法识别的格式应返回错误")
+	}
+}
+
+func TestPrepareImageRejectsTooManyPixels(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 4001, 4000))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := PrepareImage(buf.Bytes()); err == nil || !strings.Contains(err.Error(), "超出处理范围") {
+		t.Errorf("超过像素上限时应返回错误，实际: %v", err)
+	}
+}
@@ -0,0 +1,141 @@
+package wecom
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy 请求失败时的重试策略
+type RetryPolicy struct {
+	// MaxAttempts 最大尝试次数（包含首次请求），小于等于 1 表示不重试
+	MaxAttempts int
+	// InitialBackoff 首次重试前的等待时间
+	InitialBackoff time.Duration
+	// MaxBackoff 单次等待时间上限
+	MaxBackoff time.Duration
+	// Multiplier 每次重试等待时间的增长倍数
+	Multiplier float64
+	// Jitter 随机抖动比例（0~1），避免多个请求同时重试
+	Jitter float64
+	// RateLimitBackoff 触发频率限制且响应未携带 Retry-After 时的等待时间
+	RateLimitBackoff time.Duration
+}
+
+// DefaultRetryPolicy 默认重试策略
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:      3,
+	InitialBackoff:   500 * time.Millisecond,
+	MaxBackoff:       10 * time.Second,
+	Multiplier:       2,
+	Jitter:           0.2,
+	RateLimitBackoff: 5 * time.Second,
+}
+
+// NoRetry 不进行重试的策略
+var NoRetry = RetryPolicy{MaxAttempts: 1}
+
+// WithRetryPolicy 设置客户端的重试策略
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retryPolicy = policy
+	}
+}
+
+// backoff 计算第 attempt 次失败后的等待时间（attempt 从 1 开始）
+func (p RetryPolicy) backoff(attempt int, err error) time.Duration {
+	if apiErr, ok := asAPIError(err); ok && apiErr.RetryAfter > 0 {
+		return apiErr.RetryAfter
+	}
+
+	d := float64(p.InitialBackoff) * math.Pow(max(p.Multiplier, 1), float64(attempt-1))
+	if IsRateLimited(err) {
+		d = max(d, float64(p.RateLimitBackoff))
+	}
+	if p.MaxBackoff > 0 {
+		d = min(d, float64(p.MaxBackoff))
+	}
+	if p.Jitter > 0 {
+		d += d * p.Jitter * (rand.Float64()*2 - 1)
+	}
+	return time.Duration(d)
+}
+
+// ErrDeliveryUnknown 请求已完整发出但没有收到完整响应，企业微信可能已经处理了该请求。
+// 发送消息时重试可能导致群里收到重复消息，因此不会自动重试。
+var ErrDeliveryUnknown = errors.New("请求已发出但未收到完整响应，消息可能已送达")
+
+// IsRetryable 判断错误是否为可重试的临时错误：请求超时、连接被拒绝或重置、5xx、系统繁忙和频率限制。
+// TLS 证书错误、地址或协议错误以及构造请求失败等永久错误不会重试。
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	if apiErr, ok := asAPIError(err); ok {
+		if apiErr.ErrCode == ErrCodeSystemBusy || IsRateLimited(err) {
+			return true
+		}
+		return apiErr.ErrCode == 0 && apiErr.StatusCode >= http.StatusInternalServerError
+	}
+
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	// http.Client 的超时同时匹配 context.DeadlineExceeded，调用方的截止时间由 do 在等待重试前检查
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// parseRetryAfter 解析 Retry-After 响应头，支持秒数和 HTTP 日期两种格式
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// RequestStats 记录一次调用的请求统计信息
+type RequestStats struct {
+	// Attempts 实际发出的请求次数
+	Attempts int
+}
+
+type statsKey struct{}
+
+// ContextWithStats 返回携带统计信息的上下文，客户端会在请求过程中更新 stats
+func ContextWithStats(ctx context.Context, stats *RequestStats) context.Context {
+	return context.WithValue(ctx, statsKey{}, stats)
+}
+
+// statsFromContext 取出上下文中的统计信息
+func statsFromContext(ctx context.Context) *RequestStats {
+	stats, _ := ctx.Value(statsKey{}).(*RequestStats)
+	return stats
+}
+
+// sleepContext 等待指定时间，ctx 结束时提前返回
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,168 @@
+package wecom
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var fastRetry = RetryPolicy{
+	MaxAttempts:      3,
+	InitialBackoff:   time.Millisecond,
+	MaxBackoff:       5 * time.Millisecond,
+	Multiplier:       2,
+	RateLimitBackoff: time.Millisecond,
+}
+
+func TestRetryTransientErrors(t *testing.T) {
+	mock := newMockWeCom(t)
+	var calls atomic.Int32
+	mock.handler = func(w http.ResponseWriter, r *http.Request, body []byte) {
+		switch calls.Add(1) {
+		case 1:
+			http.Error(w, "bad gateway", http.StatusBadGateway)
+		case 2:
+			io.WriteString(w, `{"errcode":45009,"errmsg":"api freq out of limit"}`)
+		default:
+			io.WriteString(w, `{"errcode":0,"errmsg":"ok"}`)
+		}
+	}
+
+	stats := &RequestStats{}
+	ctx := ContextWithStats(context.Background(), stats)
+	if err := mock.client(WithRetryPolicy(fastRetry)).SendMarkdownContext(ctx, "test"); err != nil {
+		t.Fatalf("期望重试后成功，实际: %v", err)
+	}
+	if stats.Attempts != 3 {
+		t.Errorf("Attempts = %d, want 3", stats.Attempts)
+	}
+}
+
+func TestRetryStopsOnPermanentError(t *testing.T) {
+	mock := newMockWeCom(t)
+	mock.handler = func(w http.ResponseWriter, r *http.Request, body []byte) {
+		io.WriteString(w, `{"errcode":93000,"errmsg":"invalid webhook url"}`)
+	}
+
+	stats := &RequestStats{}
+	ctx := ContextWithStats(context.Background(), stats)
+	err := mock.client(WithRetryPolicy(fastRetry)).SendMarkdownContext(ctx, "test")
+	if !IsInvalidKey(err) {
+		t.Fatalf("期望返回 webhook key 无效错误，实际: %v", err)
+	}
+	if stats.Attempts != 1 {
+		t.Errorf("Attempts = %d, want 1", stats.Attempts)
+	}
+}
+
+func TestRetryStopsOnPermanentTransportError(t *testing.T) {
+	tlsServer := httptest.NewUnstartedServer(http.NotFoundHandler())
+	tlsServer.Config.ErrorLog = log.New(io.Discard, "", 0)
+	tlsServer.StartTLS()
+	t.Cleanup(tlsServer.Close)
+
+	tests := []struct {
+		name    string
+		baseURL string
+	}{
+		{"证书不受信任", tlsServer.URL},
+		{"不支持的协议", "ftp://qyapi.weixin.qq.com/cgi-bin/webhook"},
+		{"地址格式错误", "http://[::1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := &RequestStats{}
+			ctx := ContextWithStats(context.Background(), stats)
+			err := NewClient(testWebhookKey, WithBaseURL(tt.baseURL), WithRetryPolicy(fastRetry)).SendMarkdownContext(ctx, "test")
+			if err == nil || IsRetryable(err) {
+				t.Fatalf("期望返回不可重试的错误，实际: %v", err)
+			}
+			if stats.Attempts != 1 {
+				t.Errorf("Attempts = %d, want 1", stats.Attempts)
+			}
+		})
+	}
+}
+
+func TestRetryConnectionRefused(t *testing.T) {
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	stats := &RequestStats{}
+	ctx := ContextWithStats(context.Background(), stats)
+	err := NewClient(testWebhookKey, WithBaseURL(closed.URL), WithRetryPolicy(fastRetry)).SendMarkdownContext(ctx, "test")
+	if !IsRetryable(err) {
+		t.Fatalf("连接被拒绝应可重试，实际: %v", err)
+	}
+	if stats.Attempts != 3 {
+		t.Errorf("Attempts = %d, want 3", stats.Attempts)
+	}
+}
+
+func TestRetryResponseTimeout(t *testing.T) {
+	mock := newMockWeCom(t)
+	mock.handler = func(w http.ResponseWriter, r *http.Request, body []byte) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}
+	client := mock.client(WithRetryPolicy(fastRetry), WithTimeout(50*time.Millisecond))
+
+	// 消息可能已经送达，重试会导致群里收到重复消息
+	stats := &RequestStats{}
+	err := client.SendMarkdownContext(ContextWithStats(context.Background(), stats), "test")
+	if !errors.Is(err, ErrDeliveryUnknown) {
+		t.Fatalf("期望返回 ErrDeliveryUnknown，实际: %v", err)
+	}
+	if stats.Attempts != 1 {
+		t.Errorf("发送消息 Attempts = %d, want 1", stats.Attempts)
+	}
+
+	// 重复上传素材没有副作用，超时后仍然重试
+	stats = &RequestStats{}
+	if _, err := client.UploadBytes(ContextWithStats(context.Background(), stats), "a.txt", []byte("hello"), MediaTypeFile); err == nil {
+		t.Fatal("期望上传超时")
+	}
+	if stats.Attempts != 3 {
+		t.Errorf("上传素材 Attempts = %d, want 3", stats.Attempts)
+	}
+}
+
+func TestRetryRespectsDeadline(t *testing.T) {
+	mock := newMockWeCom(t)
+	mock.handler = func(w http.ResponseWriter, r *http.Request, body []byte) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	err := mock.client(WithRetryPolicy(fastRetry)).SendMarkdownContext(ctx, "test")
+	if err == nil || !IsRetryable(err) {
+		t.Fatalf("期望返回可重试错误，实际: %v", err)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Errorf("等待时间超过截止时间时应立即返回")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if d := parseRetryAfter("3"); d != 3*time.Second {
+		t.Errorf("parseRetryAfter(3) = %v", d)
+	}
+	if d := parseRetryAfter(""); d != 0 {
+		t.Errorf("parseRetryAfter(\"\") = %v", d)
+	}
+	future := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if d := parseRetryAfter(future); d <= 0 || d > time.Minute {
+		t.Errorf("parseRetryAfter(%q) = %v", future, d)
+	}
+}
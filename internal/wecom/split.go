@@ -0,0 +1,209 @@
+package wecom
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// SplitText 将超长文本消息内容拆分为多段，每段不超过 MaxTextBytes 字节
+func SplitText(content string) []string {
+	return SplitContent(content, MaxTextBytes, false)
+}
+
+// SplitMarkdown 将超长 Markdown 消息内容拆分为多段，每段不超过 MaxMarkdownBytes 字节
+func SplitMarkdown(content string) []string {
+	return SplitContent(content, MaxMarkdownBytes, true)
+}
+
+// SplitContent 将内容拆分为不超过 limit 字节的多段，并在每段末尾添加 "(1/3)" 形式的编号。
+// 优先在段落边界拆分，其次是行边界，最后才在 UTF-8 字符边界强制拆分；
+// markdown 为 true 时代码块会整体保留，过长的代码块拆分后每段都会重新补齐代码块标记。
+// 内容未超出限制时原样返回；超长内容只包含空白时返回一个空字符串，由消息校验报告内容为空。
+// 返回值至少包含一段。
+func SplitContent(content string, limit int, markdown bool) []string {
+	if len(content) <= limit {
+		return []string{content}
+	}
+
+	blocks := splitBlocks(content, markdown)
+	if len(blocks) == 0 {
+		return []string{""}
+	}
+
+	// 预留编号所需的空间，编号位数随段数增加时重新拆分
+	reserve := len(partLabel(9, 9))
+	for {
+		parts := packBlocks(blocks, limit-reserve)
+		if label := len(partLabel(len(parts), len(parts))); label > reserve {
+			reserve = label
+			continue
+		}
+		for i := range parts {
+			parts[i] += partLabel(i+1, len(parts))
+		}
+		return parts
+	}
+}
+
+// partLabel 生成分段编号
+func partLabel(i, n int) string {
+	return fmt.Sprintf("\n(%d/%d)", i, n)
+}
+
+// block 拆分的最小单元：普通段落或完整的代码块
+type block struct {
+	text string
+	// fence 代码块的起始标记行，例如 "```go"，普通段落为空
+	fence string
+}
+
+// splitBlocks 按空行拆分段落，Markdown 代码块作为独立单元保留
+func splitBlocks(content string, markdown bool) []block {
+	var blocks []block
+	var lines []string
+	fence := ""
+
+	flush := func() {
+		if len(lines) > 0 {
+			blocks = append(blocks, block{text: strings.Join(lines, "\n"), fence: fence})
+			lines = nil
+		}
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		isFence := markdown && strings.HasPrefix(strings.TrimSpace(line), "```")
+		switch {
+		case fence != "":
+			// 代码块内部，遇到结束标记时整体作为一个单元
+			lines = append(lines, line)
+			if isFence {
+				flush()
+				fence = ""
+			}
+		case isFence:
+			flush()
+			fence = strings.TrimSpace(line)
+			lines = append(lines, line)
+		case strings.TrimSpace(line) == "":
+			flush()
+		default:
+			lines = append(lines, line)
+		}
+	}
+	// 未闭合的代码块按普通段落处理
+	fence = ""
+	flush()
+
+	return blocks
+}
+
+// packBlocks 将段落依次装入不超过 limit 字节的分段，段落之间以空行分隔
+func packBlocks(blocks []block, limit int) []string {
+	var parts []string
+	cur := ""
+
+	add := func(piece, sep string) {
+		if cur != "" && len(cur)+len(sep)+len(piece) <= limit {
+			cur += sep + piece
+			return
+		}
+		if cur != "" {
+			parts = append(parts, cur)
+		}
+		cur = piece
+	}
+
+	for _, b := range blocks {
+		if len(b.text) <= limit {
+			add(b.text, "\n\n")
+			continue
+		}
+		pieces := splitBlock(b, limit)
+		for i, piece := range pieces {
+			if i == 0 {
+				add(piece, "\n\n")
+			} else {
+				add(piece, "\n")
+			}
+		}
+	}
+	if cur != "" {
+		parts = append(parts, cur)
+	}
+	return parts
+}
+
+// splitBlock 将超长段落按行拆分，代码块拆分后每段重新补齐起止标记
+func splitBlock(b block, limit int) []string {
+	if b.fence == "" {
+		return packLines(strings.Split(b.text, "\n"), limit)
+	}
+
+	lines := strings.Split(b.text, "\n")
+	inner := lines[1:]
+	if n := len(inner); n > 0 && strings.HasPrefix(strings.TrimSpace(inner[n-1]), "```") {
+		inner = inner[:n-1]
+	}
+
+	open, end := b.fence+"\n", "\n```"
+	overhead := len(open) + len(end)
+	if limit-overhead < utf8.UTFMax {
+		return packLines(lines, limit)
+	}
+
+	var pieces []string
+	for _, chunk := range packLines(inner, limit-overhead) {
+		pieces = append(pieces, open+chunk+end)
+	}
+	return pieces
+}
+
+// packLines 将多行文本装入不超过 limit 字节的分段，超长的行在字符边界强制拆分
+func packLines(lines []string, limit int) []string {
+	var pieces []string
+	cur := ""
+	started := false
+
+	for _, line := range lines {
+		for _, seg := range hardSplit(line, limit) {
+			if started && len(cur)+1+len(seg) <= limit {
+				cur += "\n" + seg
+				continue
+			}
+			if started {
+				pieces = append(pieces, cur)
+			}
+			cur, started = seg, true
+		}
+	}
+	if started {
+		pieces = append(pieces, cur)
+	}
+	return pieces
+}
+
+// hardSplit 在 UTF-8 字符边界将字符串拆分为不超过 limit 字节的片段
+func hardSplit(s string, limit int) []string {
+	if len(s) <= limit {
+		return []string{s}
+	}
+
+	var segs []string
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		if cut == 0 {
+			// limit 小于单个字符的长度，至少保留一个完整字符
+			_, cut = utf8.DecodeRuneInString(s)
+		}
+		segs = append(segs, s[:cut])
+		s = s[cut:]
+	}
+	if s != "" {
+		segs = append(segs, s)
+	}
+	return segs
+}
@@ -0,0 +1,101 @@
+package wecom
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// stripLabel 去掉分段编号
+func stripLabel(t *testing.T, part string, i, n int) string {
+	t.Helper()
+	label := fmt.Sprintf("\n(%d/%d)", i+1, n)
+	if !strings.HasSuffix(part, label) {
+		t.Fatalf("第 %d 段缺少编号 %q", i+1, label)
+	}
+	return strings.TrimSuffix(part, label)
+}
+
+func checkParts(t *testing.T, parts []string, limit int) string {
+	t.Helper()
+	var joined strings.Builder
+	for i, part := range parts {
+		if len(part) > limit {
+			t.Errorf("第 %d 段长度 %d 超过限制 %d", i+1, len(part), limit)
+		}
+		if !utf8.ValidString(part) {
+			t.Errorf("第 %d 段不是合法的 UTF-8", i+1)
+		}
+		joined.WriteString(stripLabel(t, part, i, len(parts)))
+	}
+	return joined.String()
+}
+
+func TestSplitContentShort(t *testing.T) {
+	parts := SplitText("短消息")
+	if len(parts) != 1 || parts[0] != "短消息" {
+		t.Errorf("SplitText = %q", parts)
+	}
+}
+
+func TestSplitContentBlank(t *testing.T) {
+	for _, content := range []string{strings.Repeat(" \n", 3000), strings.Repeat("\n", 5000)} {
+		if parts := SplitMarkdown(content); len(parts) != 1 || parts[0] != "" {
+			t.Errorf("SplitMarkdown(空白) = %q", parts)
+		}
+		if parts := SplitText(content); len(parts) != 1 || parts[0] != "" {
+			t.Errorf("SplitText(空白) = %q", parts)
+		}
+	}
+}
+
+func TestSplitContentParagraphs(t *testing.T) {
+	var paragraphs []string
+	for i := 0; i < 20; i++ {
+		paragraphs = append(paragraphs, fmt.Sprintf("第%d段：%s", i, strings.Repeat("企业微信机器人", 10)))
+	}
+	content := strings.Join(paragraphs, "\n\n")
+
+	parts := SplitText(content)
+	if len(parts) < 2 {
+		t.Fatalf("期望拆分为多段，实际 %d 段", len(parts))
+	}
+	joined := checkParts(t, parts, MaxTextBytes)
+	for _, p := range paragraphs {
+		if !strings.Contains(joined, p) {
+			t.Errorf("段落被拆开: %q", p)
+		}
+	}
+}
+
+func TestSplitContentLongLine(t *testing.T) {
+	content := strings.Repeat("中", 2000)
+	parts := SplitText(content)
+	joined := checkParts(t, parts, MaxTextBytes)
+	if strings.ReplaceAll(joined, "\n", "") != content {
+		t.Errorf("拆分后内容不一致")
+	}
+}
+
+func TestSplitMarkdownCodeBlock(t *testing.T) {
+	var code []string
+	for i := 0; i < 300; i++ {
+		code = append(code, fmt.Sprintf("fmt.Println(\"line %d\")", i))
+	}
+	content := "## 构建日志\n\n```go\n" + strings.Join(code, "\n") + "\n```\n\n结束"
+
+	parts := SplitMarkdown(content)
+	if len(parts) < 2 {
+		t.Fatalf("期望拆分为多段，实际 %d 段", len(parts))
+	}
+	checkParts(t, parts, MaxMarkdownBytes)
+	for i, part := range parts {
+		if strings.Count(part, "```")%2 != 0 {
+			t.Errorf("第 %d 段代码块标记未闭合", i+1)
+		}
+	}
+	if !strings.Contains(parts[1], "```go\n") {
+		t.Errorf("续段应重新打开代码块: %q", parts[1][:40])
+	}
+}
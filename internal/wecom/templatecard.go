@@ -0,0 +1,375 @@
+package wecom
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// 模板卡片类型
+const (
+	CardTypeTextNotice = "text_notice"
+	CardTypeNewsNotice = "news_notice"
+)
+
+// 模板卡片的数量限制
+const (
+	// MaxHorizontalContents 二级标题+文本列表最多条数
+	MaxHorizontalContents = 6
+	// MaxJumps 跳转指引列表最多条数
+	MaxJumps = 3
+	// MaxVerticalContents 卡片二级垂直内容最多条数
+	MaxVerticalContents = 4
+)
+
+// 图片样式宽高比范围
+const (
+	MinCardImageAspectRatio = 1.3
+	MaxCardImageAspectRatio = 2.25
+)
+
+// CardSource 卡片来源样式信息
+type CardSource struct {
+	IconURL string `json:"icon_url,omitempty"`
+	Desc    string `json:"desc,omitempty"`
+	// DescColor 来源文字颜色：0 灰色（默认），1 黑色，2 红色，3 绿色
+	DescColor int `json:"desc_color,omitempty"`
+}
+
+// validate 校验来源样式
+func (s *CardSource) validate(field string) error {
+	if s.DescColor < 0 || s.DescColor > 3 {
+		return invalid(field+".desc_color", "必须为 0~3，实际为 %d", s.DescColor)
+	}
+	return nil
+}
+
+// CardMainTitle 模板卡片主标题
+type CardMainTitle struct {
+	Title string `json:"title,omitempty"`
+	Desc  string `json:"desc,omitempty"`
+}
+
+// CardEmphasisContent 关键数据样式
+type CardEmphasisContent struct {
+	Title string `json:"title,omitempty"`
+	Desc  string `json:"desc,omitempty"`
+}
+
+// CardQuoteArea 引用文献样式
+type CardQuoteArea struct {
+	// Type 点击事件类型：0 没有点击事件，1 跳转URL，2 跳转小程序
+	Type      int    `json:"type,omitempty"`
+	URL       string `json:"url,omitempty"`
+	AppID     string `json:"appid,omitempty"`
+	PagePath  string `json:"pagepath,omitempty"`
+	Title     string `json:"title,omitempty"`
+	QuoteText string `json:"quote_text,omitempty"`
+}
+
+// validate 校验引用样式
+func (q *CardQuoteArea) validate(field string) error {
+	switch q.Type {
+	case 0:
+	case 1:
+		if q.URL == "" {
+			return invalid(field+".url", "跳转类型为 1 时不能为空")
+		}
+	case 2:
+		if q.AppID == "" {
+			return invalid(field+".appid", "跳转类型为 2 时不能为空")
+		}
+	default:
+		return invalid(field+".type", "必须为 0~2，实际为 %d", q.Type)
+	}
+	return nil
+}
+
+// CardHorizontalContent 二级标题+文本
+type CardHorizontalContent struct {
+	// Type 链接类型：0 普通文本，1 跳转URL，2 下载附件，3 点击跳转成员详情
+	Type    int    `json:"type,omitempty"`
+	KeyName string `json:"keyname"`
+	Value   string `json:"value,omitempty"`
+	URL     string `json:"url,omitempty"`
+	MediaID string `json:"media_id,omitempty"`
+	UserID  string `json:"userid,omitempty"`
+}
+
+// validate 校验二级标题+文本
+func (h *CardHorizontalContent) validate(field string) error {
+	if h.KeyName == "" {
+		return invalid(field+".keyname", "不能为空")
+	}
+	switch h.Type {
+	case 0:
+	case 1:
+		if h.URL == "" {
+			return invalid(field+".url", "链接类型为 1 时不能为空")
+		}
+	case 2:
+		if h.MediaID == "" {
+			return invalid(field+".media_id", "链接类型为 2 时不能为空")
+		}
+	case 3:
+		if h.UserID == "" {
+			return invalid(field+".userid", "链接类型为 3 时不能为空")
+		}
+	default:
+		return invalid(field+".type", "必须为 0~3，实际为 %d", h.Type)
+	}
+	return nil
+}
+
+// CardJump 跳转指引
+type CardJump struct {
+	// Type 跳转类型：0 不跳转，1 跳转URL，2 跳转小程序
+	Type     int    `json:"type,omitempty"`
+	Title    string `json:"title"`
+	URL      string `json:"url,omitempty"`
+	AppID    string `json:"appid,omitempty"`
+	PagePath string `json:"pagepath,omitempty"`
+}
+
+// validate 校验跳转指引
+func (j *CardJump) validate(field string) error {
+	if j.Title == "" {
+		return invalid(field+".title", "不能为空")
+	}
+	switch j.Type {
+	case 0:
+	case 1:
+		if j.URL == "" {
+			return invalid(field+".url", "跳转类型为 1 时不能为空")
+		}
+	case 2:
+		if j.AppID == "" {
+			return invalid(field+".appid", "跳转类型为 2 时不能为空")
+		}
+	default:
+		return invalid(field+".type", "必须为 0~2，实际为 %d", j.Type)
+	}
+	return nil
+}
+
+// CardAction 整体卡片的点击跳转事件
+type CardAction struct {
+	// Type 跳转类型：1 跳转URL，2 打开小程序
+	Type     int    `json:"type"`
+	URL      string `json:"url,omitempty"`
+	AppID    string `json:"appid,omitempty"`
+	PagePath string `json:"pagepath,omitempty"`
+}
+
+// validate 校验跳转事件
+func (a *CardAction) validate(field string) error {
+	switch a.Type {
+	case 1:
+		if a.URL == "" {
+			return invalid(field+".url", "跳转类型为 1 时不能为空")
+		}
+	case 2:
+		if a.AppID == "" {
+			return invalid(field+".appid", "跳转类型为 2 时不能为空")
+		}
+	default:
+		return invalid(field+".type", "必须为 1（跳转URL）或 2（打开小程序），实际为 %d", a.Type)
+	}
+	return nil
+}
+
+// validateCardLists 校验各类卡片共有的列表字段
+func validateCardLists(horizontal []CardHorizontalContent, jumps []CardJump) error {
+	if len(horizontal) > MaxHorizontalContents {
+		return invalid("horizontal_content_list", "最多 %d 条，实际为 %d 条", MaxHorizontalContents, len(horizontal))
+	}
+	for i := range horizontal {
+		if err := horizontal[i].validate(fmt.Sprintf("horizontal_content_list[%d]", i)); err != nil {
+			return err
+		}
+	}
+	if len(jumps) > MaxJumps {
+		return invalid("jump_list", "最多 %d 条，实际为 %d 条", MaxJumps, len(jumps))
+	}
+	for i := range jumps {
+		if err := jumps[i].validate(fmt.Sprintf("jump_list[%d]", i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TextNoticeCard 文本通知模板卡片
+type TextNoticeCard struct {
+	Source                *CardSource             `json:"source,omitempty"`
+	MainTitle             *CardMainTitle          `json:"main_title,omitempty"`
+	EmphasisContent       *CardEmphasisContent    `json:"emphasis_content,omitempty"`
+	QuoteArea             *CardQuoteArea          `json:"quote_area,omitempty"`
+	SubTitleText          string                  `json:"sub_title_text,omitempty"`
+	HorizontalContentList []CardHorizontalContent `json:"horizontal_content_list,omitempty"`
+	JumpList              []CardJump              `json:"jump_list,omitempty"`
+	CardAction            *CardAction             `json:"card_action"`
+}
+
+// MsgType 实现 Message 接口
+func (c *TextNoticeCard) MsgType() string { return MsgTypeTemplateCard }
+
+// MarshalJSON 序列化时补充 card_type 字段
+func (c *TextNoticeCard) MarshalJSON() ([]byte, error) {
+	type card TextNoticeCard
+	return json.Marshal(struct {
+		CardType string `json:"card_type"`
+		*card
+	}{CardTypeTextNotice, (*card)(c)})
+}
+
+// Validate 实现 Message 接口
+func (c *TextNoticeCard) Validate() error {
+	if (c.MainTitle == nil || c.MainTitle.Title == "") && c.SubTitleText == "" {
+		return invalid("main_title.title", "与 sub_title_text 不能同时为空")
+	}
+	if c.Source != nil {
+		if err := c.Source.validate("source"); err != nil {
+			return err
+		}
+	}
+	if c.QuoteArea != nil {
+		if err := c.QuoteArea.validate("quote_area"); err != nil {
+			return err
+		}
+	}
+	if err := validateCardLists(c.HorizontalContentList, c.JumpList); err != nil {
+		return err
+	}
+	if c.CardAction == nil {
+		return invalid("card_action", "不能为空")
+	}
+	return c.CardAction.validate("card_action")
+}
+
+// CardImage 图片样式
+type CardImage struct {
+	URL string `json:"url"`
+	// AspectRatio 图片的宽高比，取值范围 1.3~2.25，默认 1.3
+	AspectRatio float64 `json:"aspect_ratio,omitempty"`
+}
+
+// validate 校验图片样式
+func (i *CardImage) validate(field string) error {
+	if i.URL == "" {
+		return invalid(field+".url", "不能为空")
+	}
+	if i.AspectRatio != 0 && (i.AspectRatio < MinCardImageAspectRatio || i.AspectRatio > MaxCardImageAspectRatio) {
+		return invalid(field+".aspect_ratio", "必须在 %.2f~%.2f 之间，实际为 %.2f", MinCardImageAspectRatio, MaxCardImageAspectRatio, i.AspectRatio)
+	}
+	return nil
+}
+
+// CardImageTextArea 左图右文样式
+type CardImageTextArea struct {
+	// Type 点击事件类型：0 没有点击事件，1 跳转URL，2 跳转小程序
+	Type     int    `json:"type,omitempty"`
+	URL      string `json:"url,omitempty"`
+	AppID    string `json:"appid,omitempty"`
+	PagePath string `json:"pagepath,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Desc     string `json:"desc,omitempty"`
+	ImageURL string `json:"image_url"`
+}
+
+// validate 校验左图右文样式
+func (a *CardImageTextArea) validate(field string) error {
+	if a.ImageURL == "" {
+		return invalid(field+".image_url", "不能为空")
+	}
+	switch a.Type {
+	case 0:
+	case 1:
+		if a.URL == "" {
+			return invalid(field+".url", "跳转类型为 1 时不能为空")
+		}
+	case 2:
+		if a.AppID == "" {
+			return invalid(field+".appid", "跳转类型为 2 时不能为空")
+		}
+	default:
+		return invalid(field+".type", "必须为 0~2，实际为 %d", a.Type)
+	}
+	return nil
+}
+
+// CardVerticalContent 卡片二级垂直内容
+type CardVerticalContent struct {
+	Title string `json:"title"`
+	Desc  string `json:"desc,omitempty"`
+}
+
+// NewsNoticeCard 图文展示模板卡片
+type NewsNoticeCard struct {
+	Source                *CardSource             `json:"source,omitempty"`
+	MainTitle             *CardMainTitle          `json:"main_title"`
+	CardImage             *CardImage              `json:"card_image,omitempty"`
+	ImageTextArea         *CardImageTextArea      `json:"image_text_area,omitempty"`
+	QuoteArea             *CardQuoteArea          `json:"quote_area,omitempty"`
+	VerticalContentList   []CardVerticalContent   `json:"vertical_content_list,omitempty"`
+	HorizontalContentList []CardHorizontalContent `json:"horizontal_content_list,omitempty"`
+	JumpList              []CardJump              `json:"jump_list,omitempty"`
+	CardAction            *CardAction             `json:"card_action"`
+}
+
+// MsgType 实现 Message 接口
+func (c *NewsNoticeCard) MsgType() string { return MsgTypeTemplateCard }
+
+// MarshalJSON 序列化时补充 card_type 字段
+func (c *NewsNoticeCard) MarshalJSON() ([]byte, error) {
+	type card NewsNoticeCard
+	return json.Marshal(struct {
+		CardType string `json:"card_type"`
+		*card
+	}{CardTypeNewsNotice, (*card)(c)})
+}
+
+// Validate 实现 Message 接口
+func (c *NewsNoticeCard) Validate() error {
+	if c.MainTitle == nil || c.MainTitle.Title == "" {
+		return invalid("main_title.title", "不能为空")
+	}
+	if c.Source != nil {
+		if err := c.Source.validate("source"); err != nil {
+			return err
+		}
+	}
+	if c.CardImage != nil {
+		if err := c.CardImage.validate("card_image"); err != nil {
+			return err
+		}
+	}
+	if c.ImageTextArea != nil {
+		if err := c.ImageTextArea.validate("image_text_area"); err != nil {
+			return err
+		}
+	}
+	// 企业微信要求图片样式和左图右文样式至少提供其中之一
+	if c.CardImage == nil && c.ImageTextArea == nil {
+		return invalid("card_image", "与 image_text_area 至少提供其中之一")
+	}
+	if c.QuoteArea != nil {
+		if err := c.QuoteArea.validate("quote_area"); err != nil {
+			return err
+		}
+	}
+	if len(c.VerticalContentList) > MaxVerticalContents {
+		return invalid("vertical_content_list", "最多 %d 条，实际为 %d 条", MaxVerticalContents, len(c.VerticalContentList))
+	}
+	for i, content := range c.VerticalContentList {
+		if content.Title == "" {
+			return invalid(fmt.Sprintf("vertical_content_list[%d].title", i), "不能为空")
+		}
+	}
+	if err := validateCardLists(c.HorizontalContentList, c.JumpList); err != nil {
+		return err
+	}
+	if c.CardAction == nil {
+		return invalid("card_action", "不能为空")
+	}
+	return c.CardAction.validate("card_action")
+}
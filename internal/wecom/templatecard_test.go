@@ -0,0 +1,131 @@
+package wecom
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func validTextNoticeCard() *TextNoticeCard {
+	return &TextNoticeCard{
+		Source:    &CardSource{Desc: "监控系统", DescColor: 2},
+		MainTitle: &CardMainTitle{Title: "服务告警", Desc: "生产环境"},
+		HorizontalContentList: []CardHorizontalContent{
+			{KeyName: "服务", Value: "api"},
+			{KeyName: "详情", Value: "查看", Type: 1, URL: "https://example.com"},
+		},
+		JumpList:   []CardJump{{Type: 1, Title: "控制台", URL: "https://example.com"}},
+		CardAction: &CardAction{Type: 1, URL: "https://example.com"},
+	}
+}
+
+func TestTextNoticeCardMarshal(t *testing.T) {
+	data, err := MarshalMessage(validTextNoticeCard())
+	if err != nil {
+		t.Fatalf("MarshalMessage failed: %v", err)
+	}
+
+	var payload struct {
+		MsgType      string                 `json:"msgtype"`
+		TemplateCard map[string]interface{} `json:"template_card"`
+	}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		t.Fatalf("解析序列化结果失败: %v", err)
+	}
+	if payload.MsgType != MsgTypeTemplateCard || payload.TemplateCard["card_type"] != CardTypeTextNotice {
+		t.Errorf("payload = %s", data)
+	}
+	if _, ok := payload.TemplateCard["quote_area"]; ok {
+		t.Errorf("未设置的字段不应序列化: %s", data)
+	}
+}
+
+func TestTextNoticeCardValidate(t *testing.T) {
+	if err := validTextNoticeCard().Validate(); err != nil {
+		t.Fatalf("期望校验通过，实际: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(c *TextNoticeCard)
+		field  string
+	}{
+		{"no title", func(c *TextNoticeCard) { c.MainTitle = nil }, "main_title.title"},
+		{"bad color", func(c *TextNoticeCard) { c.Source.DescColor = 5 }, "source.desc_color"},
+		{"too many horizontal", func(c *TextNoticeCard) {
+			c.HorizontalContentList = make([]CardHorizontalContent, MaxHorizontalContents+1)
+		}, "horizontal_content_list"},
+		{"horizontal without url", func(c *TextNoticeCard) { c.HorizontalContentList[1].URL = "" }, "horizontal_content_list[1].url"},
+		{"too many jumps", func(c *TextNoticeCard) { c.JumpList = make([]CardJump, MaxJumps+1) }, "jump_list"},
+		{"quote without appid", func(c *TextNoticeCard) { c.QuoteArea = &CardQuoteArea{Type: 2} }, "quote_area.appid"},
+		{"no card action", func(c *TextNoticeCard) { c.CardAction = nil }, "card_action"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			card := validTextNoticeCard()
+			tt.modify(card)
+			var vErr *ValidationError
+			if err := card.Validate(); !errors.As(err, &vErr) || vErr.Field != tt.field {
+				t.Fatalf("期望字段 %s 校验失败，实际: %v", tt.field, err)
+			}
+		})
+	}
+}
+
+func TestNewsNoticeCardValidate(t *testing.T) {
+	valid := func() *NewsNoticeCard {
+		return &NewsNoticeCard{
+			MainTitle:           &CardMainTitle{Title: "版本发布"},
+			CardImage:           &CardImage{URL: "https://example.com/cover.png", AspectRatio: 2.25},
+			VerticalContentList: []CardVerticalContent{{Title: "新功能", Desc: "支持语音消息"}},
+			CardAction:          &CardAction{Type: 1, URL: "https://example.com"},
+		}
+	}
+	if err := valid().Validate(); err != nil {
+		t.Fatalf("期望校验通过，实际: %v", err)
+	}
+
+	// 图片样式和左图右文样式可以同时提供
+	both := valid()
+	both.ImageTextArea = &CardImageTextArea{Title: "t", ImageURL: "https://example.com/thumb.png"}
+	if err := both.Validate(); err != nil {
+		t.Errorf("同时提供 card_image 和 image_text_area 时应校验通过，实际: %v", err)
+	}
+
+	data, err := MarshalMessage(valid())
+	if err != nil {
+		t.Fatalf("MarshalMessage failed: %v", err)
+	}
+	var payload struct {
+		TemplateCard map[string]interface{} `json:"template_card"`
+	}
+	json.Unmarshal(data, &payload)
+	if payload.TemplateCard["card_type"] != CardTypeNewsNotice {
+		t.Errorf("payload = %s", data)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(c *NewsNoticeCard)
+		field  string
+	}{
+		{"no title", func(c *NewsNoticeCard) { c.MainTitle = &CardMainTitle{Desc: "desc"} }, "main_title.title"},
+		{"bad aspect ratio", func(c *NewsNoticeCard) { c.CardImage.AspectRatio = 3 }, "card_image.aspect_ratio"},
+		{"image text without image", func(c *NewsNoticeCard) { c.ImageTextArea = &CardImageTextArea{Title: "t"} }, "image_text_area.image_url"},
+		{"no image", func(c *NewsNoticeCard) { c.CardImage = nil }, "card_image"},
+		{"too many vertical", func(c *NewsNoticeCard) {
+			c.VerticalContentList = make([]CardVerticalContent, MaxVerticalContents+1)
+		}, "vertical_content_list"},
+		{"vertical without title", func(c *NewsNoticeCard) { c.VerticalContentList[0].Title = "" }, "vertical_content_list[0].title"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			card := valid()
+			tt.modify(card)
+			var vErr *ValidationError
+			if err := card.Validate(); !errors.As(err, &vErr) || vErr.Field != tt.field {
+				t.Fatalf("期望字段 %s 校验失败，实际: %v", tt.field, err)
+			}
+		})
+	}
+}